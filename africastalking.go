@@ -0,0 +1,214 @@
+package serverutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Africa's Talking SMS endpoints
+const (
+	AfricasTalkingSMSURL        = "https://api.africastalking.com/version1/messaging"
+	AfricasTalkingSandboxSMSURL = "https://api.sandbox.africastalking.com/version1/messaging"
+)
+
+// DefaultPhoneCountryCode is the country calling code (Kenya) given to phone numbers in the
+// national format e.g 0711 223 344
+const DefaultPhoneCountryCode = "254"
+
+// AfricasTalkingSMSSender sends SMS through Africa's Talking
+type AfricasTalkingSMSSender struct {
+	username    string
+	apiKey      string
+	senderID    string
+	countryCode string
+	sendURL     string
+	httpClient  *http.Client
+}
+
+// NewAfricasTalkingSMSSender returns an Africa's Talking SMS sender.
+//
+// The sender ID is optional; the Africa's Talking default is used when it is empty.
+// The `sandbox` username sends messages through the sandbox API. Recipients in the national
+// format get the DefaultPhoneCountryCode unless another one is set with WithDefaultCountryCode.
+func NewAfricasTalkingSMSSender(username, apiKey, senderID string) *AfricasTalkingSMSSender {
+	sendURL := AfricasTalkingSMSURL
+	if username == "sandbox" {
+		sendURL = AfricasTalkingSandboxSMSURL
+	}
+	return &AfricasTalkingSMSSender{
+		username:    username,
+		apiKey:      apiKey,
+		senderID:    senderID,
+		countryCode: DefaultPhoneCountryCode,
+		sendURL:     sendURL,
+		httpClient: &http.Client{
+			Timeout:   InterserviceRequestTimeout,
+			Transport: NewTracingTransport(NewMetricsTransport(nil)),
+		},
+	}
+}
+
+// NewAfricasTalkingSMSSenderFromEnv returns an Africa's Talking SMS sender configured from
+// the AIT_* environment variables
+func NewAfricasTalkingSMSSenderFromEnv() (*AfricasTalkingSMSSender, error) {
+	username, err := GetEnvVar(AfricasTalkingUsernameEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := GetEnvVar(AfricasTalkingAPIKeyEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	senderID, _ := GetEnvVar(AfricasTalkingSenderIDEnvVarName)
+	return NewAfricasTalkingSMSSender(username, apiKey, senderID), nil
+}
+
+// WithSendURL points the sender to another endpoint e.g a test server
+func (s *AfricasTalkingSMSSender) WithSendURL(url string) *AfricasTalkingSMSSender {
+	s.sendURL = url
+	return s
+}
+
+// WithDefaultCountryCode sets the country calling code (e.g 256) given to recipients in the
+// national format
+func (s *AfricasTalkingSMSSender) WithDefaultCountryCode(countryCode string) *AfricasTalkingSMSSender {
+	s.countryCode = countryCode
+	return s
+}
+
+type africasTalkingSMSResponse struct {
+	SMSMessageData struct {
+		Message    string `json:"Message"`
+		Recipients []struct {
+			StatusCode int    `json:"statusCode"`
+			Number     string `json:"number"`
+			Status     string `json:"status"`
+			MessageID  string `json:"messageId"`
+		} `json:"Recipients"`
+	} `json:"SMSMessageData"`
+}
+
+// SendSMS implements the SMSSender interface
+func (s *AfricasTalkingSMSSender) SendSMS(ctx context.Context, to []string, message string) ([]SMSResult, error) {
+	if len(to) == 0 {
+		return nil, fmt.Errorf("a SMS needs at least one recipient")
+	}
+	if message == "" {
+		return nil, fmt.Errorf("a SMS can't be empty")
+	}
+
+	recipients := make([]string, 0, len(to))
+	for _, phone := range to {
+		normalized, err := NormalizePhoneNumber(phone, s.countryCode)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, normalized)
+	}
+
+	form := url.Values{}
+	form.Set("username", s.username)
+	form.Set("to", strings.Join(recipients, ","))
+	form.Set("message", message)
+	if s.senderID != "" {
+		form.Set("from", s.senderID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.sendURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to compose the Africa's Talking request: %w", err)
+	}
+	req.Header.Set("apiKey", s.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send SMS through Africa's Talking: %w", err)
+	}
+	defer closeBody(resp)
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the Africa's Talking response: %w", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("the Africa's Talking API responded with status %d: %s", resp.StatusCode, string(content))
+	}
+
+	data := africasTalkingSMSResponse{}
+	err = json.Unmarshal(content, &data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the Africa's Talking response: %w", err)
+	}
+
+	results := []SMSResult{}
+	for _, recipient := range data.SMSMessageData.Recipients {
+		result := SMSResult{
+			MessageID:   recipient.MessageID,
+			PhoneNumber: recipient.Number,
+			Status:      SMSStatusQueued,
+		}
+		// 100 (Processed), 101 (Sent) and 102 (Queued) are successes, the rest are failures
+		if recipient.StatusCode < 100 || recipient.StatusCode > 102 {
+			result.Status = SMSStatusFailed
+			result.Reason = recipient.Status
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// AfricasTalkingDeliveryReportHandler receives Africa's Talking SMS delivery reports and
+// passes them to the supplied function.
+//
+// Africa's Talking does not sign its callbacks, so the handler should be mounted on a path
+// that is hard to guess or behind another form of authentication.
+func AfricasTalkingDeliveryReportHandler(update SMSDeliveryReportFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, DefaultMaxBodyBytes)
+		err := r.ParseForm()
+		if err != nil {
+			WriteJSONResponse(w, ErrorMap(fmt.Errorf("unable to parse the delivery report: %w", err)), http.StatusBadRequest)
+			return
+		}
+
+		report := SMSDeliveryReport{
+			MessageID:     r.PostForm.Get("id"),
+			PhoneNumber:   r.PostForm.Get("phoneNumber"),
+			Status:        africasTalkingDeliveryStatus(r.PostForm.Get("status")),
+			FailureReason: r.PostForm.Get("failureReason"),
+		}
+		if report.MessageID == "" {
+			WriteJSONResponse(w, ErrorMap(fmt.Errorf("the delivery report has no message ID")), http.StatusBadRequest)
+			return
+		}
+
+		err = update(r.Context(), report)
+		if err != nil {
+			// a failure status makes Africa's Talking retry the callback
+			WriteJSONResponse(w, ErrorMap(err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func africasTalkingDeliveryStatus(status string) SMSStatus {
+	switch status {
+	case "Success":
+		return SMSStatusDelivered
+	case "Sent", "Submitted":
+		return SMSStatusSent
+	case "Buffered":
+		return SMSStatusQueued
+	default:
+		// Rejected, Failed and AbsentSubscriber
+		return SMSStatusFailed
+	}
+}
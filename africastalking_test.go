@@ -0,0 +1,152 @@
+package serverutils_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAfricasTalkingSMSSender_SendSMS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "api-key", r.Header.Get("apiKey"))
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, "app", r.PostForm.Get("username"))
+		assert.Equal(t, "+254711223344,+254700000000", r.PostForm.Get("to"))
+		assert.Equal(t, "SAVANNAH", r.PostForm.Get("from"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(`{"SMSMessageData": {"Message": "Sent to 1/2", "Recipients": [
+			{"statusCode": 101, "number": "+254711223344", "status": "Success", "messageId": "ATPid_1"},
+			{"statusCode": 406, "number": "+254700000000", "status": "UserInBlacklist", "messageId": "None"}
+		]}}`))
+		assert.Nil(t, err)
+	}))
+	defer srv.Close()
+
+	sender := serverutils.NewAfricasTalkingSMSSender("app", "api-key", "SAVANNAH").WithSendURL(srv.URL)
+	results, err := sender.SendSMS(context.Background(), []string{"0711 223 344", "+254700000000"}, "Hello")
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "ATPid_1", results[0].MessageID)
+	assert.Equal(t, serverutils.SMSStatusQueued, results[0].Status)
+	assert.Equal(t, serverutils.SMSStatusFailed, results[1].Status)
+	assert.Equal(t, "UserInBlacklist", results[1].Reason)
+
+	_, err = sender.SendSMS(context.Background(), nil, "Hello")
+	assert.NotNil(t, err)
+	_, err = sender.SendSMS(context.Background(), []string{"+254711223344"}, "")
+	assert.NotNil(t, err)
+	_, err = sender.SendSMS(context.Background(), []string{"+254711223344", "not a number"}, "Hello")
+	assert.NotNil(t, err)
+}
+
+func TestAfricasTalkingSMSSender_WithDefaultCountryCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, "+256772123456", r.PostForm.Get("to"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(`{"SMSMessageData": {"Message": "Sent to 1/1", "Recipients": [
+			{"statusCode": 101, "number": "+256772123456", "status": "Success", "messageId": "ATPid_1"}
+		]}}`))
+		assert.Nil(t, err)
+	}))
+	defer srv.Close()
+
+	sender := serverutils.NewAfricasTalkingSMSSender("app", "api-key", "").
+		WithSendURL(srv.URL).
+		WithDefaultCountryCode("256")
+	results, err := sender.SendSMS(context.Background(), []string{"0772 123 456"}, "Hello")
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestAfricasTalkingSMSSender_SendSMS_Unauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	sender := serverutils.NewAfricasTalkingSMSSender("app", "wrong-key", "").WithSendURL(srv.URL)
+	_, err := sender.SendSMS(context.Background(), []string{"+254711223344"}, "Hello")
+	assert.NotNil(t, err)
+}
+
+func TestNewAfricasTalkingSMSSenderFromEnv(t *testing.T) {
+	setEnv(t, map[string]string{
+		serverutils.AfricasTalkingUsernameEnvVarName: "sandbox",
+		serverutils.AfricasTalkingAPIKeyEnvVarName:   "",
+	})
+	_, err := serverutils.NewAfricasTalkingSMSSenderFromEnv()
+	assert.NotNil(t, err)
+
+	setEnv(t, map[string]string{serverutils.AfricasTalkingAPIKeyEnvVarName: "api-key"})
+	sender, err := serverutils.NewAfricasTalkingSMSSenderFromEnv()
+	assert.Nil(t, err)
+	assert.NotNil(t, sender)
+}
+
+func TestAfricasTalkingDeliveryReportHandler(t *testing.T) {
+	var reports []serverutils.SMSDeliveryReport
+	handler := serverutils.AfricasTalkingDeliveryReportHandler(func(ctx context.Context, report serverutils.SMSDeliveryReport) error {
+		if report.MessageID == "ATPid_broken" {
+			return fmt.Errorf("unable to update the message")
+		}
+		reports = append(reports, report)
+		return nil
+	})
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantStatus int
+		wantReport serverutils.SMSStatus
+	}{
+		{
+			name:       "delivered",
+			form:       url.Values{"id": {"ATPid_1"}, "status": {"Success"}, "phoneNumber": {"+254711223344"}},
+			wantStatus: http.StatusOK,
+			wantReport: serverutils.SMSStatusDelivered,
+		},
+		{
+			name:       "failed",
+			form:       url.Values{"id": {"ATPid_2"}, "status": {"Failed"}, "failureReason": {"InsufficientCredit"}},
+			wantStatus: http.StatusOK,
+			wantReport: serverutils.SMSStatusFailed,
+		},
+		{
+			name:       "no message ID",
+			form:       url.Values{"status": {"Success"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "update fails",
+			form:       url.Values{"id": {"ATPid_broken"}, "status": {"Success"}},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports = nil
+			req := httptest.NewRequest(http.MethodPost, "/sms/delivery-reports", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rw := httptest.NewRecorder()
+
+			handler.ServeHTTP(rw, req)
+			assert.Equal(t, tt.wantStatus, rw.Code)
+			if tt.wantReport != "" {
+				assert.Len(t, reports, 1)
+				assert.Equal(t, tt.wantReport, reports[0].Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package serverutils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// ICSContentType is the content type of iCalendar (RFC 5545) payloads
+const ICSContentType = "text/calendar; charset=utf-8; method=REQUEST"
+
+const (
+	icsUTCLayout   = "20060102T150405Z"
+	icsLocalLayout = "20060102T150405"
+	icsDateLayout  = "20060102"
+
+	// lines longer than 75 octets are folded
+	icsMaxLineLength = 75
+)
+
+// CalendarEvent is an event e.g an appointment that is exported to or parsed from an iCalendar payload
+type CalendarEvent struct {
+	// UID identifies the event across updates; reuse it to update or cancel an invite
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+
+	// Organizer and Attendees are email addresses; RenderICS rejects invalid ones
+	Organizer string
+	Attendees []string
+}
+
+// RenderICS renders the supplied events as an iCalendar (RFC 5545) payload.
+// The product ID identifies the application that produced it e.g "-//Be.Well//Bookings//EN".
+func RenderICS(productID string, events ...CalendarEvent) ([]byte, error) {
+	now := time.Now()
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + escapeICSText(productID),
+		"CALSCALE:GREGORIAN",
+		"METHOD:REQUEST",
+	}
+	for _, event := range events {
+		if event.UID == "" {
+			return nil, fmt.Errorf("calendar events must have a UID")
+		}
+		if event.Start.IsZero() {
+			return nil, fmt.Errorf("the calendar event %s has no start time", event.UID)
+		}
+		if !event.End.IsZero() && event.End.Before(event.Start) {
+			return nil, fmt.Errorf("the calendar event %s ends before it starts", event.UID)
+		}
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+escapeICSText(event.UID),
+			"DTSTAMP:"+now.UTC().Format(icsUTCLayout),
+		)
+		if event.AllDay {
+			lines = append(lines, "DTSTART;VALUE=DATE:"+event.Start.Format(icsDateLayout))
+			if !event.End.IsZero() {
+				lines = append(lines, "DTEND;VALUE=DATE:"+event.End.Format(icsDateLayout))
+			}
+		} else {
+			lines = append(lines, "DTSTART:"+event.Start.UTC().Format(icsUTCLayout))
+			if !event.End.IsZero() {
+				lines = append(lines, "DTEND:"+event.End.UTC().Format(icsUTCLayout))
+			}
+		}
+		if event.Summary != "" {
+			lines = append(lines, "SUMMARY:"+escapeICSText(event.Summary))
+		}
+		if event.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escapeICSText(event.Description))
+		}
+		if event.Location != "" {
+			lines = append(lines, "LOCATION:"+escapeICSText(event.Location))
+		}
+		if event.Organizer != "" {
+			organizer, err := icsMailto(event.Organizer)
+			if err != nil {
+				return nil, fmt.Errorf("the organizer of the calendar event %s is invalid: %w", event.UID, err)
+			}
+			lines = append(lines, "ORGANIZER:"+organizer)
+		}
+		for _, attendee := range event.Attendees {
+			mailto, err := icsMailto(attendee)
+			if err != nil {
+				return nil, fmt.Errorf("an attendee of the calendar event %s is invalid: %w", event.UID, err)
+			}
+			lines = append(lines, "ATTENDEE;RSVP=TRUE:"+mailto)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(foldICSLine(line))
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// CalendarInviteAttachment renders the supplied events as an `invite.ics` email attachment
+func CalendarInviteAttachment(productID string, events ...CalendarEvent) (*EmailAttachment, error) {
+	content, err := RenderICS(productID, events...)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailAttachment{Filename: "invite.ics", ContentType: ICSContentType, Content: content}, nil
+}
+
+// ParseICS parses the events in an iCalendar (RFC 5545) payload e.g an inbound email attachment.
+//
+// Only the properties of CalendarEvent are read; recurrence rules are ignored.
+func ParseICS(data []byte) ([]CalendarEvent, error) {
+	lines, err := unfoldICSLines(data)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []CalendarEvent{}
+	var event *CalendarEvent
+	for _, line := range lines {
+		name, params, value, err := parseICSLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = &CalendarEvent{}
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if event == nil {
+				return nil, fmt.Errorf("the calendar has an END:VEVENT without a BEGIN:VEVENT")
+			}
+			events = append(events, *event)
+			event = nil
+			continue
+		case event == nil:
+			continue
+		}
+
+		switch name {
+		case "UID":
+			event.UID = unescapeICSText(value)
+		case "SUMMARY":
+			event.Summary = unescapeICSText(value)
+		case "DESCRIPTION":
+			event.Description = unescapeICSText(value)
+		case "LOCATION":
+			event.Location = unescapeICSText(value)
+		case "ORGANIZER":
+			event.Organizer = icsEmailAddress(value)
+		case "ATTENDEE":
+			event.Attendees = append(event.Attendees, icsEmailAddress(value))
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseICSTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTSTART of calendar event %s: %w", event.UID, err)
+			}
+		case "DTEND":
+			event.End, _, err = parseICSTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTEND of calendar event %s: %w", event.UID, err)
+			}
+		}
+	}
+	if event != nil {
+		return nil, fmt.Errorf("the calendar has a BEGIN:VEVENT without an END:VEVENT")
+	}
+	return events, nil
+}
+
+// unfoldICSLines joins the folded (continuation) lines of an iCalendar payload
+func unfoldICSLines(data []byte) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseICSLine splits a content line e.g `DTSTART;TZID=Africa/Nairobi:20230101T090000`
+// into its name, parameters and value
+func parseICSLine(line string) (string, map[string]string, string, error) {
+	head, value, found := strings.Cut(line, ":")
+	if !found {
+		return "", nil, "", fmt.Errorf("invalid calendar line %q", line)
+	}
+	parts := strings.Split(head, ";")
+	params := map[string]string{}
+	for _, param := range parts[1:] {
+		key, val, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, value, nil
+}
+
+func parseICSTime(params map[string]string, value string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(icsDateLayout) {
+		t, err := time.Parse(icsDateLayout, value)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icsUTCLayout, value)
+		return t, false, err
+	}
+
+	// times in unknown time zones e.g the Windows names used by Outlook are read as UTC
+	// rather than failing the whole calendar
+	location := time.UTC
+	if tzid, ok := params["TZID"]; ok {
+		loc, err := time.LoadLocation(tzid)
+		if err == nil {
+			location = loc
+		}
+	}
+	t, err := time.ParseInLocation(icsLocalLayout, value, location)
+	return t, false, err
+}
+
+// icsMailto validates an email address and returns it as a mailto URI. Only the bare address is
+// kept so that names or line breaks can't inject calendar properties.
+func icsMailto(address string) (string, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(parsed.Address, "\r\n") {
+		return "", fmt.Errorf("%q contains a line break", address)
+	}
+	return "mailto:" + parsed.Address, nil
+}
+
+func icsEmailAddress(value string) string {
+	if len(value) > len("mailto:") && strings.EqualFold(value[:len("mailto:")], "mailto:") {
+		return value[len("mailto:"):]
+	}
+	return value
+}
+
+var (
+	icsTextEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	icsTextUnescaper = strings.NewReplacer(
+		`\\`, `\`,
+		`\;`, ";",
+		`\,`, ",",
+		`\n`, "\n",
+		`\N`, "\n",
+	)
+)
+
+func escapeICSText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
+
+func unescapeICSText(s string) string {
+	return icsTextUnescaper.Replace(s)
+}
+
+// foldICSLine splits lines longer than 75 octets, without splitting multi-byte characters
+func foldICSLine(line string) string {
+	if len(line) <= icsMaxLineLength {
+		return line
+	}
+
+	var buf strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > icsMaxLineLength {
+			buf.WriteString("\r\n ")
+			length = 1
+		}
+		buf.WriteRune(r)
+		length += size
+	}
+	return buf.String()
+}
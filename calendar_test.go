@@ -0,0 +1,144 @@
+package serverutils_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderICS_RoundTrip(t *testing.T) {
+	start := time.Date(2023, 3, 14, 9, 30, 0, 0, time.UTC)
+	event := serverutils.CalendarEvent{
+		UID:         "appointment-1@bewell.co.ke",
+		Summary:     "Consultation; Dr. Otieno, Nairobi",
+		Description: "Please arrive 10 minutes early.\nBring your insurance card. " + strings.Repeat("Karibu sana ", 10),
+		Location:    "Ngong Road",
+		Start:       start,
+		End:         start.Add(30 * time.Minute),
+		Organizer:   "bookings@bewell.co.ke",
+		Attendees:   []string{"patient@example.com"},
+	}
+
+	content, err := serverutils.RenderICS("-//Be.Well//Bookings//EN", event)
+	assert.Nil(t, err)
+	for _, line := range strings.Split(string(content), "\r\n") {
+		assert.True(t, len(line) <= 75)
+	}
+	assert.Contains(t, string(content), "DTSTART:20230314T093000Z\r\n")
+	assert.Contains(t, string(content), `SUMMARY:Consultation\; Dr. Otieno\, Nairobi`)
+
+	events, err := serverutils.ParseICS(content)
+	assert.Nil(t, err)
+	assert.Equal(t, []serverutils.CalendarEvent{event}, events)
+}
+
+func TestRenderICS_LineBreaks(t *testing.T) {
+	content, err := serverutils.RenderICS("-//Test//EN", serverutils.CalendarEvent{
+		UID:         "1",
+		Start:       time.Now(),
+		Description: "first\rsecond\r\nthird\nfourth",
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `DESCRIPTION:first\nsecond\nthird\nfourth`)
+	assert.NotContains(t, strings.ReplaceAll(string(content), "\r\n", ""), "\r")
+}
+
+func TestRenderICS_Invalid(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name  string
+		event serverutils.CalendarEvent
+	}{
+		{name: "no UID", event: serverutils.CalendarEvent{Start: start}},
+		{name: "no start", event: serverutils.CalendarEvent{UID: "1"}},
+		{name: "ends before start", event: serverutils.CalendarEvent{UID: "1", Start: start, End: start.Add(-time.Hour)}},
+		{name: "invalid organizer", event: serverutils.CalendarEvent{UID: "1", Start: start, Organizer: "not an email"}},
+		{name: "injected attendee", event: serverutils.CalendarEvent{UID: "1", Start: start, Attendees: []string{"a@example.com\r\nATTENDEE:mailto:b@example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := serverutils.RenderICS("-//Test//EN", tt.event)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestParseICS(t *testing.T) {
+	nairobi, err := time.LoadLocation("Africa/Nairobi")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		ics     string
+		want    []serverutils.CalendarEvent
+		wantErr bool
+	}{
+		{
+			name: "local time and folded lines",
+			ics: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nSUMMARY:Follow\n  up\nDTSTART;TZID=Africa/Nairobi:20230314T093000\n" +
+				"ORGANIZER;CN=Clinic:MAILTO:clinic@example.com\nEND:VEVENT\nEND:VCALENDAR\n",
+			want: []serverutils.CalendarEvent{{
+				UID:       "1",
+				Summary:   "Follow up",
+				Start:     time.Date(2023, 3, 14, 9, 30, 0, 0, nairobi),
+				Organizer: "clinic@example.com",
+			}},
+		},
+		{
+			name: "all day",
+			ics:  "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:2\r\nDTSTART;VALUE=DATE:20230314\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: []serverutils.CalendarEvent{{
+				UID:    "2",
+				Start:  time.Date(2023, 3, 14, 0, 0, 0, 0, time.UTC),
+				AllDay: true,
+			}},
+		},
+		{
+			name: "unknown time zone",
+			ics:  "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:4\nDTSTART;TZID=E. Africa Standard Time:20230314T093000\nEND:VEVENT\nEND:VCALENDAR\n",
+			want: []serverutils.CalendarEvent{{
+				UID:   "4",
+				Start: time.Date(2023, 3, 14, 9, 30, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name:    "unterminated event",
+			ics:     "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:3\nEND:VCALENDAR\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid start",
+			ics:     "BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := serverutils.ParseICS([]byte(tt.ics))
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, len(tt.want), len(got))
+			for i := range tt.want {
+				assert.True(t, tt.want[i].Start.Equal(got[i].Start))
+				got[i].Start = tt.want[i].Start
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCalendarInviteAttachment(t *testing.T) {
+	attachment, err := serverutils.CalendarInviteAttachment(
+		"-//Test//EN", serverutils.CalendarEvent{UID: "1", Start: time.Now()},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "invite.ics", attachment.Filename)
+	assert.Equal(t, serverutils.ICSContentType, attachment.ContentType)
+	assert.Contains(t, string(attachment.Content), "BEGIN:VEVENT")
+}
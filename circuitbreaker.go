@@ -0,0 +1,199 @@
+package serverutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// Circuit breaker defaults
+const (
+	// DefaultCircuitBreakerFailureThreshold is the number of consecutive failed requests to a
+	// host that open its circuit
+	DefaultCircuitBreakerFailureThreshold = 5
+
+	// DefaultCircuitBreakerOpenTimeout is how long a circuit stays open before a probe request
+	// is let through
+	DefaultCircuitBreakerOpenTimeout = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for requests to a host whose circuit is open
+var ErrCircuitOpen = errors.New("the circuit breaker is open")
+
+// CircuitState is the state of the circuit of an upstream host
+type CircuitState string
+
+// Circuit states
+const (
+	// CircuitClosed lets requests through
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen fails requests fast without calling the host
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen lets a single probe request through to find out if the host has recovered
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// CircuitBreakerTransport is a http.RoundTripper that stops calling upstream hosts that keep
+// failing, so that callers fail fast instead of waiting on an outage.
+//
+// Transport errors and 5xx responses are failures. After a number of consecutive failures
+// the host's circuit opens and its requests fail with ErrCircuitOpen. Once the open timeout
+// has passed a single probe request is let through: the circuit closes if it succeeds and
+// opens again if it fails. State changes are recorded by the circuit breaker metrics.
+type CircuitBreakerTransport struct {
+	base             http.RoundTripper
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewCircuitBreakerTransport wraps the supplied transport with a circuit breaker per host.
+// http.DefaultTransport is used when the supplied transport is nil and the defaults are
+// used when the threshold or timeout are not positive.
+func NewCircuitBreakerTransport(base http.RoundTripper, failureThreshold int, openTimeout time.Duration) *CircuitBreakerTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if failureThreshold <= 0 {
+		failureThreshold = DefaultCircuitBreakerFailureThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = DefaultCircuitBreakerOpenTimeout
+	}
+	return &CircuitBreakerTransport{
+		base:             base,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		circuits:         make(map[string]*circuit),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *CircuitBreakerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	host := r.URL.Host
+	err := t.allow(r, host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil && errors.Is(r.Context().Err(), context.Canceled) {
+		// the caller gave up, that says nothing about the health of the host. Timeouts,
+		// including http.Client timeouts which are context deadlines, are failures.
+		t.release(host)
+		return resp, err
+	}
+	t.record(r, host, err == nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+// State returns the state of the circuit of a host
+func (t *CircuitBreakerTransport) State(host string) CircuitState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.circuits[host]
+	if !ok {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= t.openTimeout {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+func (t *CircuitBreakerTransport) allow(r *http.Request, host string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.circuits[host]
+	if !ok {
+		c = &circuit{state: CircuitClosed}
+		t.circuits[host] = c
+	}
+
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < t.openTimeout {
+			return fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+		}
+		t.transition(r, host, c, CircuitHalfOpen)
+		c.probing = true
+	case CircuitHalfOpen:
+		if c.probing {
+			return fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+func (t *CircuitBreakerTransport) record(r *http.Request, host string, success bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.circuits[host]
+	c.probing = false
+	if success {
+		c.failures = 0
+		if c.state != CircuitClosed {
+			t.transition(r, host, c, CircuitClosed)
+		}
+		return
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || (c.state == CircuitClosed && c.failures >= t.failureThreshold) {
+		c.openedAt = time.Now()
+		t.transition(r, host, c, CircuitOpen)
+	}
+}
+
+// release lets another probe through when a probe was abandoned
+func (t *CircuitBreakerTransport) release(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.circuits[host].probing = false
+}
+
+// transition must be called with the lock held
+func (t *CircuitBreakerTransport) transition(r *http.Request, host string, c *circuit, state CircuitState) {
+	c.state = state
+	Logger().WithFields(log.Fields{"host": host, "state": state}).Warn("Circuit breaker state changed")
+	RecordCircuitBreakerState(r, host, state)
+}
+
+// RecordCircuitBreakerState records a change of the state of the circuit of a host
+func RecordCircuitBreakerState(r *http.Request, host string, state CircuitState) {
+	ctx, _ := tag.New(r.Context(),
+		tag.Insert(OutboundHost, host),
+		tag.Insert(CircuitBreakerStateKey, string(state)),
+	)
+
+	value := int64(0)
+	switch state {
+	case CircuitHalfOpen:
+		value = 1
+	case CircuitOpen:
+		value = 2
+	}
+	stats.Record(ctx, CircuitBreakerStateChange.M(value))
+}
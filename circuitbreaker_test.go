@@ -0,0 +1,139 @@
+package serverutils_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubTransport struct {
+	calls int
+	fail  bool
+}
+
+func (s *stubTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	s.calls++
+	if s.fail {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+}
+
+func TestCircuitBreakerTransport(t *testing.T) {
+	base := &stubTransport{fail: true}
+	transport := serverutils.NewCircuitBreakerTransport(base, 2, 50*time.Millisecond)
+	client := &http.Client{Transport: transport}
+
+	get := func(url string) error {
+		resp, err := client.Get(url)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	// consecutive failures open the circuit
+	assert.NotNil(t, get("http://edi.example.com/claims"))
+	assert.Equal(t, serverutils.CircuitClosed, transport.State("edi.example.com"))
+	assert.NotNil(t, get("http://edi.example.com/claims"))
+	assert.Equal(t, serverutils.CircuitOpen, transport.State("edi.example.com"))
+
+	// requests fail fast while the circuit is open
+	err := get("http://edi.example.com/claims")
+	assert.True(t, errors.Is(err, serverutils.ErrCircuitOpen))
+	assert.Equal(t, 2, base.calls)
+
+	// other hosts have their own circuit
+	base.fail = false
+	assert.Nil(t, get("http://erp.example.com/items"))
+
+	// a failed probe opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, serverutils.CircuitHalfOpen, transport.State("edi.example.com"))
+	base.fail = true
+	assert.NotNil(t, get("http://edi.example.com/claims"))
+	assert.True(t, errors.Is(get("http://edi.example.com/claims"), serverutils.ErrCircuitOpen))
+
+	// a successful probe closes it
+	time.Sleep(60 * time.Millisecond)
+	base.fail = false
+	assert.Nil(t, get("http://edi.example.com/claims"))
+	assert.Equal(t, serverutils.CircuitClosed, transport.State("edi.example.com"))
+}
+
+func TestCircuitBreakerTransport_ServerErrors(t *testing.T) {
+	statuses := []int{http.StatusInternalServerError, http.StatusBadRequest}
+	for _, status := range statuses {
+		t.Run(fmt.Sprint(status), func(t *testing.T) {
+			transport := serverutils.NewCircuitBreakerTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}), 1, time.Minute)
+
+			req, err := http.NewRequest(http.MethodGet, "http://edi.example.com/claims", nil)
+			assert.Nil(t, err)
+			resp, err := transport.RoundTrip(req)
+			assert.Nil(t, err)
+			_ = resp.Body.Close()
+
+			// only server errors count as failures
+			want := serverutils.CircuitClosed
+			if status >= http.StatusInternalServerError {
+				want = serverutils.CircuitOpen
+			}
+			assert.Equal(t, want, transport.State("edi.example.com"))
+		})
+	}
+}
+
+func TestCircuitBreakerTransport_Timeouts(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	transport := serverutils.NewCircuitBreakerTransport(nil, 1, time.Minute)
+	client := &http.Client{Timeout: 50 * time.Millisecond, Transport: transport}
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	// a hung upstream trips the breaker
+	_, err := client.Get(srv.URL)
+	assert.NotNil(t, err)
+	assert.Equal(t, serverutils.CircuitOpen, transport.State(host))
+}
+
+func TestCircuitBreakerTransport_CallerCancelled(t *testing.T) {
+	transport := serverutils.NewCircuitBreakerTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	}), 1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://edi.example.com/claims", nil)
+	assert.Nil(t, err)
+	cancel()
+	_, err = transport.RoundTrip(req)
+	assert.NotNil(t, err)
+
+	// a caller that gives up says nothing about the health of the host
+	assert.Equal(t, serverutils.CircuitClosed, transport.State("edi.example.com"))
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
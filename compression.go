@@ -0,0 +1,218 @@
+package serverutils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultGzipMinSize is the size in bytes below which responses are not worth compressing
+const DefaultGzipMinSize = 1024
+
+// MaxGzipRequestBytes is the size limit of decompressed gzip request bodies. It guards against
+// small requests that expand to exhaust memory (gzip bombs).
+const MaxGzipRequestBytes = 10 << 20 // 10 MiB
+
+// DefaultGzipContentTypes are the content types compressed by GzipMiddleware when none are
+// supplied. Entries ending with a `/` match all the subtypes of a type.
+var DefaultGzipContentTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	"text/",
+}
+
+// GzipMiddleware compresses the responses of clients that accept gzip and decompresses gzip
+// encoded request bodies.
+//
+// Only responses of at least minSize bytes with one of the supplied content types are
+// compressed; DefaultGzipMinSize and DefaultGzipContentTypes are used when they are not
+// supplied. Responses that already have a Content-Encoding e.g those written by
+// WriteNegotiatedJSONResponse are left as they are. Requests that ask for a protocol upgrade
+// e.g websockets are passed through untouched so that the connection can be hijacked.
+//
+// Request bodies that are not valid gzip are rejected with a 400 status. Decompressed request
+// bodies are limited to MaxGzipRequestBytes; reading beyond the limit fails.
+func GzipMiddleware(minSize int, contentTypes ...string) func(http.Handler) http.Handler {
+	if minSize <= 0 {
+		minSize = DefaultGzipMinSize
+	}
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultGzipContentTypes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Upgrade") != "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				if strings.EqualFold(strings.TrimSpace(r.Header.Get("Content-Encoding")), "gzip") {
+					body, err := gzip.NewReader(r.Body)
+					if err != nil {
+						err = fmt.Errorf("the request body is not valid gzip: %w", err)
+						WriteJSONResponse(w, ErrorMap(err), http.StatusBadRequest)
+						return
+					}
+					r.Body = http.MaxBytesReader(w, body, MaxGzipRequestBytes)
+					r.Header.Del("Content-Encoding")
+					r.Header.Del("Content-Length")
+					r.ContentLength = -1
+				}
+
+				w.Header().Add("Vary", "Accept-Encoding")
+				if r.Method == http.MethodHead || !AcceptsGzip(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				gw := &gzipResponseWriter{
+					ResponseWriter: w,
+					minSize:        minSize,
+					contentTypes:   contentTypes,
+					status:         http.StatusOK,
+				}
+				defer gw.close()
+				next.ServeHTTP(gw, r)
+			},
+		)
+	}
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether the response
+// should be compressed
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize      int
+	contentTypes []string
+
+	status      int
+	wroteHeader bool
+	started     bool
+	buf         []byte
+	gz          *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if w.started {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		err := w.start(true)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher e.g for streamed responses
+func (w *gzipResponseWriter) Flush() {
+	if !w.started {
+		err := w.start(true)
+		if err != nil {
+			Logger().WithFields(log.Fields{"error": err}).Error("Unable to write gzipped response")
+			return
+		}
+	}
+	if w.gz != nil {
+		err := w.gz.Flush()
+		if err != nil {
+			Logger().WithFields(log.Fields{"error": err}).Error("Unable to write gzipped response")
+			return
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// start writes the header and the buffered body, compressing it if it is eligible
+func (w *gzipResponseWriter) start(largeEnough bool) error {
+	w.started = true
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if largeEnough && w.compressible() {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *gzipResponseWriter) compressible() bool {
+	switch {
+	case w.status < http.StatusOK,
+		w.status == http.StatusNoContent,
+		w.status == http.StatusNotModified,
+		w.Header().Get("Content-Encoding") != "":
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, contentType := range w.contentTypes {
+		if mediaType == contentType || (strings.HasSuffix(contentType, "/") && strings.HasPrefix(mediaType, contentType)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *gzipResponseWriter) close() {
+	if !w.started {
+		if !w.wroteHeader {
+			// nothing was written, the server sends the implicit 200
+			return
+		}
+		err := w.start(false)
+		if err != nil {
+			Logger().WithFields(log.Fields{"error": err}).Error("Unable to write response")
+		}
+		return
+	}
+	if w.gz != nil {
+		err := w.gz.Close()
+		if err != nil {
+			Logger().WithFields(log.Fields{"error": err}).Error("Unable to write gzipped response")
+		}
+	}
+}
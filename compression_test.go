@@ -0,0 +1,161 @@
+package serverutils_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzipMiddleware_Response(t *testing.T) {
+	large := `{"items":"` + strings.Repeat("feed item ", 200) + `"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		status         int
+		wantGzip       bool
+	}{
+		{name: "large JSON", acceptEncoding: "gzip", contentType: "application/json", body: large, status: http.StatusCreated, wantGzip: true},
+		{name: "large text without a content type", acceptEncoding: "gzip", body: strings.Repeat("text ", 300), status: http.StatusOK, wantGzip: true},
+		{name: "client does not accept gzip", contentType: "application/json", body: large, status: http.StatusOK},
+		{name: "small JSON", acceptEncoding: "gzip", contentType: "application/json", body: `{"ok":true}`, status: http.StatusOK},
+		{name: "content type not allowed", acceptEncoding: "gzip", contentType: "image/png", body: large, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := serverutils.GzipMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				// written in two parts to cross the size threshold mid response
+				_, _ = io.WriteString(w, tt.body[:len(tt.body)/2])
+				_, _ = io.WriteString(w, tt.body[len(tt.body)/2:])
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/feed", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.status, rw.Code)
+			assert.Equal(t, "Accept-Encoding", rw.Header().Get("Vary"))
+
+			body := rw.Body.Bytes()
+			if tt.wantGzip {
+				assert.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+				gz, err := gzip.NewReader(bytes.NewReader(body))
+				assert.Nil(t, err)
+				body, err = io.ReadAll(gz)
+				assert.Nil(t, err)
+			} else {
+				assert.Empty(t, rw.Header().Get("Content-Encoding"))
+			}
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
+
+func TestGzipMiddleware_AlreadyEncoded(t *testing.T) {
+	payload := map[string]string{"items": strings.Repeat("feed item ", 200)}
+	handler := serverutils.GzipMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverutils.WriteNegotiatedJSONResponse(w, r, payload, http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/feed", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+
+	// the response is only compressed once
+	gz, err := gzip.NewReader(rw.Body)
+	assert.Nil(t, err)
+	body, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "feed item")
+}
+
+func TestGzipMiddleware_Request(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte(`{"name":"Jane"}`))
+	assert.Nil(t, err)
+	assert.Nil(t, gz.Close())
+
+	handler := serverutils.GzipMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"name":"Jane"}`, string(body))
+		assert.Empty(t, r.Header.Get("Content-Encoding"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name       string
+		body       []byte
+		wantStatus int
+	}{
+		{name: "gzip body", body: compressed.Bytes(), wantStatus: http.StatusNoContent},
+		{name: "invalid gzip body", body: []byte(`{"name":"Jane"}`), wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/profiles", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Encoding", "gzip")
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+			assert.Equal(t, tt.wantStatus, rw.Code)
+		})
+	}
+}
+
+func TestGzipMiddleware_RequestTooLarge(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write(make([]byte, serverutils.MaxGzipRequestBytes+1))
+	assert.Nil(t, err)
+	assert.Nil(t, gz.Close())
+
+	handler := serverutils.GzipMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		var maxBytesError *http.MaxBytesError
+		assert.True(t, errors.As(err, &maxBytesError))
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/profiles", bytes.NewReader(compressed.Bytes()))
+	req.Header.Set("Content-Encoding", "gzip")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rw.Code)
+}
+
+func TestGzipMiddleware_Upgrade(t *testing.T) {
+	handler := serverutils.GzipMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the response writer is not wrapped so the connection can be hijacked
+		_, ok := w.(*httptest.ResponseRecorder)
+		assert.True(t, ok)
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusSwitchingProtocols, rw.Code)
+	assert.Empty(t, rw.Header().Get("Content-Encoding"))
+}
@@ -0,0 +1,162 @@
+package serverutils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Config holds the typed values of the environment variables used by this package
+type Config struct {
+	Port                 int
+	Environment          string
+	GoogleCloudProjectID string
+	SentryDSN            string
+	TraceSampleRate      float64
+	JaegerURL            string
+	Debug                bool
+	IsRunningTests       bool
+	CORS                 CORSOptions
+}
+
+// ConfigError lists every problem found when loading the configuration
+type ConfigError struct {
+	Problems []string
+}
+
+// Error implements the error interface
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(e.Problems, "; "))
+}
+
+// LoadConfig reads and validates the environment variables used by this package.
+//
+// The supplied env files (e.g `.env`) are loaded first; variables that are already set in
+// the environment take precedence over the ones in the files.
+// All problems are reported together in a *ConfigError so that a misconfigured service
+// fails fast at startup.
+func LoadConfig(envFiles ...string) (*Config, error) {
+	for _, file := range envFiles {
+		if err := LoadEnvFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	problems := []string{}
+	required := func(name string) string {
+		val, err := GetEnvVar(name)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		return val
+	}
+	optionalBool := func(name string) bool {
+		val, err := GetEnvVar(name)
+		if err != nil {
+			return false
+		}
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("the environment variable '%s' is not a boolean: %s", name, val))
+		}
+		return parsed
+	}
+
+	config := &Config{
+		Environment:          required(Environment),
+		GoogleCloudProjectID: required(GoogleCloudProjectIDEnvVarName),
+		SentryDSN:            os.Getenv(DSNEnvVarName),
+		JaegerURL:            os.Getenv(JaegerURLEnvVarName),
+		Debug:                optionalBool(DebugEnvVarName),
+		IsRunningTests:       optionalBool(IsRunningTestsEnvVarName),
+		CORS:                 CORSOptionsFromEnv(),
+	}
+
+	switch config.Environment {
+	case "", StagingEnv, TestingEnv, DemoEnv, ProdEnv:
+	default:
+		problems = append(problems, fmt.Sprintf(
+			"the environment variable '%s' should be one of %s, %s, %s or %s, got %s",
+			Environment, StagingEnv, TestingEnv, DemoEnv, ProdEnv, config.Environment,
+		))
+	}
+
+	port, err := GetEnvVar(PortEnvVarName)
+	if err != nil {
+		port = DefaultPort
+	}
+	config.Port, err = strconv.Atoi(port)
+	if err != nil || config.Port <= 0 || config.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("the environment variable '%s' is not a valid port: %s", PortEnvVarName, port))
+	}
+
+	config.TraceSampleRate = 1.0
+	if rate, err := GetEnvVar(TraceSampleRateEnvVarName); err == nil {
+		config.TraceSampleRate, err = strconv.ParseFloat(rate, 64)
+		if err != nil || config.TraceSampleRate < 0 || config.TraceSampleRate > 1 {
+			problems = append(problems, fmt.Sprintf(
+				"the environment variable '%s' should be a number between 0 and 1, got %s", TraceSampleRateEnvVarName, rate,
+			))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &ConfigError{Problems: problems}
+	}
+	return config, nil
+}
+
+// LoadEnvFile sets the environment variables defined in a `.env` style file.
+//
+// Each line holds a `KEY=value` pair, optionally prefixed with `export` (as in env.sh).
+// Blank lines and lines starting with `#` are ignored and values may be quoted.
+// Variables that are already set in the environment are not overridden.
+func LoadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open env file %s: %w", path, err)
+	}
+	defer func() {
+		err := f.Close()
+		if err != nil {
+			Logger().Println(err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return fmt.Errorf("invalid line %d in env file %s", lineNumber, path)
+		}
+
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("unable to set %s from env file %s: %w", key, path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read env file %s: %w", path, err)
+	}
+	return nil
+}
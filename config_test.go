@@ -0,0 +1,117 @@
+package serverutils_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// setEnv sets the supplied environment variables and restores their initial values
+// when the test completes
+func setEnv(t *testing.T, env map[string]string) {
+	for name, value := range env {
+		initial, set := os.LookupEnv(name)
+		if value == "" {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, value)
+		}
+		name := name
+		t.Cleanup(func() {
+			if set {
+				os.Setenv(name, initial)
+				return
+			}
+			os.Unsetenv(name)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantErr      bool
+		wantProblems int
+	}{
+		{
+			name: "valid configuration",
+			env: map[string]string{
+				serverutils.Environment:                    serverutils.StagingEnv,
+				serverutils.GoogleCloudProjectIDEnvVarName: "test-project",
+				serverutils.PortEnvVarName:                 "9000",
+				serverutils.TraceSampleRateEnvVarName:      "0.5",
+				serverutils.DebugEnvVarName:                "true",
+			},
+			wantErr: false,
+		},
+		{
+			name: "every problem is reported",
+			env: map[string]string{
+				serverutils.Environment:                    "local",
+				serverutils.GoogleCloudProjectIDEnvVarName: "",
+				serverutils.PortEnvVarName:                 "not-a-port",
+				serverutils.TraceSampleRateEnvVarName:      "2",
+				serverutils.DebugEnvVarName:                "maybe",
+			},
+			wantErr:      true,
+			wantProblems: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+
+			config, err := serverutils.LoadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				var configErr *serverutils.ConfigError
+				assert.True(t, errors.As(err, &configErr))
+				assert.Len(t, configErr.Problems, tt.wantProblems)
+				return
+			}
+			assert.Equal(t, 9000, config.Port)
+			assert.Equal(t, 0.5, config.TraceSampleRate)
+			assert.True(t, config.Debug)
+			assert.Equal(t, "test-project", config.GoogleCloudProjectID)
+		})
+	}
+}
+
+func TestLoadConfig_EnvFile(t *testing.T) {
+	setEnv(t, map[string]string{
+		serverutils.Environment:                    "",
+		serverutils.GoogleCloudProjectIDEnvVarName: "from-environment",
+		serverutils.PortEnvVarName:                 "",
+	})
+
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# Application settings
+export ENVIRONMENT=testing
+GOOGLE_CLOUD_PROJECT="from-file"
+PORT='8081'
+`
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	t.Cleanup(func() { os.Unsetenv(serverutils.Environment) })
+
+	config, err := serverutils.LoadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, serverutils.TestingEnv, config.Environment)
+	assert.Equal(t, 8081, config.Port)
+	// variables set in the environment take precedence
+	assert.Equal(t, "from-environment", config.GoogleCloudProjectID)
+
+	_, err = serverutils.LoadConfig(filepath.Join(t.TempDir(), "missing.env"))
+	assert.NotNil(t, err)
+
+	invalid := filepath.Join(t.TempDir(), "invalid.env")
+	assert.Nil(t, os.WriteFile(invalid, []byte("NOT A PAIR\n"), 0600))
+	assert.NotNil(t, serverutils.LoadEnvFile(invalid))
+}
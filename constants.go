@@ -1,5 +1,7 @@
 package serverutils
 
+import "time"
+
 const (
 	// AppName is the name of "this server"
 	AppName = "api-gateway"
@@ -45,4 +47,89 @@ const (
 
 	// TraceSampleRateEnvVarName indicates the percentage of transactions to be captured when doing performance monitoring
 	TraceSampleRateEnvVarName = "SENTRY_TRACE_SAMPLE_RATE"
+
+	// JaegerURLEnvVarName is the URL of the Jaeger collector that traces are exported to
+	JaegerURLEnvVarName = "JAEGER_URL"
+
+	// InterserviceJWTSecretEnvVarName is the shared secret used to sign and verify inter-service JWTs
+	InterserviceJWTSecretEnvVarName = "INTERSERVICE_JWT_SECRET"
+
+	// JWTSecretEnvVarName is the shared secret used to sign and verify JWTs with HMAC SHA-256 (HS256)
+	JWTSecretEnvVarName = "JWT_SECRET"
+
+	// JWTPrivateKeyEnvVarName is the PEM encoded RSA private key used to sign JWTs (RS256)
+	JWTPrivateKeyEnvVarName = "JWT_PRIVATE_KEY"
+
+	// JWTPublicKeyEnvVarName is the PEM encoded RSA public key used to verify JWTs (RS256)
+	// by services that do not issue them
+	JWTPublicKeyEnvVarName = "JWT_PUBLIC_KEY"
+
+	// UpdateGoldenFilesEnvVarName is used to determine if golden files should be rewritten
+	// with the actual output of the tests instead of being compared with it
+	UpdateGoldenFilesEnvVarName = "UPDATE_GOLDEN_FILES"
+
+	// ServiceURLEnvVarSuffix is appended to a service's name to get the environment variable
+	// that holds its base URL e.g ONBOARDING_SERVICE_URL
+	ServiceURLEnvVarSuffix = "_SERVICE_URL"
+
+	// CORSAllowedOriginsEnvVarName is a comma separated list of the origins allowed to make cross origin requests
+	CORSAllowedOriginsEnvVarName = "CORS_ALLOWED_ORIGINS"
+
+	// CORSAllowedHeadersEnvVarName is a comma separated list of the headers allowed in cross origin requests
+	CORSAllowedHeadersEnvVarName = "CORS_ALLOWED_HEADERS"
+
+	// CORSAllowedMethodsEnvVarName is a comma separated list of the methods allowed in cross origin requests
+	CORSAllowedMethodsEnvVarName = "CORS_ALLOWED_METHODS"
+
+	// CORSMaxAgeEnvVarName is the number of seconds for which browsers may cache a preflight response
+	CORSMaxAgeEnvVarName = "CORS_MAX_AGE"
+
+	// SendGridAPIKeyEnvVarName is the API key used to send emails through SendGrid
+	SendGridAPIKeyEnvVarName = "SENDGRID_API_KEY"
+
+	// SMTPHostEnvVarName is the host of the SMTP server used to send emails
+	SMTPHostEnvVarName = "SMTP_HOST"
+
+	// SMTPPortEnvVarName is the port of the SMTP server used to send emails
+	SMTPPortEnvVarName = "SMTP_PORT"
+
+	// SMTPUsernameEnvVarName is the username used to authenticate to the SMTP server
+	SMTPUsernameEnvVarName = "SMTP_USERNAME"
+
+	// SMTPPasswordEnvVarName is the password used to authenticate to the SMTP server
+	SMTPPasswordEnvVarName = "SMTP_PASSWORD"
+
+	// AfricasTalkingUsernameEnvVarName is the Africa's Talking app username; `sandbox` uses the sandbox API
+	AfricasTalkingUsernameEnvVarName = "AIT_USERNAME"
+
+	// AfricasTalkingAPIKeyEnvVarName is the Africa's Talking API key
+	AfricasTalkingAPIKeyEnvVarName = "AIT_API_KEY"
+
+	// AfricasTalkingSenderIDEnvVarName is the optional short code or alphanumeric sender ID of SMS
+	AfricasTalkingSenderIDEnvVarName = "AIT_SENDER_ID"
+
+	// WhatsAppAccessTokenEnvVarName is the access token used to call the WhatsApp Business Cloud API
+	WhatsAppAccessTokenEnvVarName = "WHATSAPP_ACCESS_TOKEN"
+
+	// WhatsAppPhoneNumberIDEnvVarName is the ID of the WhatsApp Business phone number that sends messages
+	WhatsAppPhoneNumberIDEnvVarName = "WHATSAPP_PHONE_NUMBER_ID"
+)
+
+// Server timeouts used by StartServer
+const (
+	// ServerReadTimeout is the maximum duration for reading an entire request, including the body
+	ServerReadTimeout = 15 * time.Second
+
+	// ServerReadHeaderTimeout is the maximum duration for reading the request headers
+	ServerReadHeaderTimeout = 5 * time.Second
+
+	// ServerWriteTimeout is the maximum duration before timing out writes of the response
+	ServerWriteTimeout = 60 * time.Second
+
+	// ServerIdleTimeout is the maximum amount of time to wait for the next request when keep-alives are enabled
+	ServerIdleTimeout = 120 * time.Second
+
+	// ServerShutdownTimeout is the maximum amount of time to wait for in-flight requests to drain
+	// on shutdown. Cloud Run allows 10 seconds between SIGTERM and SIGKILL.
+	ServerShutdownTimeout = 10 * time.Second
 )
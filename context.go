@@ -0,0 +1,52 @@
+package serverutils
+
+import "context"
+
+// contextKey is the type of the keys of the values this package stores in a context
+type contextKey string
+
+const (
+	interserviceClaimsContextKey = contextKey("interservice_claims")
+	cloudTaskContextKey          = contextKey("cloud_task")
+	jwtClaimsContextKey          = contextKey("jwt_claims")
+	dataLoadersContextKey        = contextKey("data_loaders")
+	flavourContextKey            = contextKey("flavour")
+	organizationIDContextKey     = contextKey("organization_id")
+	locationIDContextKey         = contextKey("location_id")
+)
+
+// WithFlavour returns a copy of the context that carries the flavour e.g PRO or CONSUMER of
+// the app that made a request
+func WithFlavour(ctx context.Context, flavour string) context.Context {
+	return context.WithValue(ctx, flavourContextKey, flavour)
+}
+
+// FlavourFromContext returns the flavour added by WithFlavour
+func FlavourFromContext(ctx context.Context) (string, bool) {
+	flavour, ok := ctx.Value(flavourContextKey).(string)
+	return flavour, ok && flavour != ""
+}
+
+// WithOrganizationID returns a copy of the context that carries the ID of the organisation
+// a request is made on behalf of
+func WithOrganizationID(ctx context.Context, organizationID string) context.Context {
+	return context.WithValue(ctx, organizationIDContextKey, organizationID)
+}
+
+// OrganizationIDFromContext returns the organisation ID added by WithOrganizationID
+func OrganizationIDFromContext(ctx context.Context) (string, bool) {
+	organizationID, ok := ctx.Value(organizationIDContextKey).(string)
+	return organizationID, ok && organizationID != ""
+}
+
+// WithLocationID returns a copy of the context that carries the ID of the location e.g the
+// branch or facility a request is made from
+func WithLocationID(ctx context.Context, locationID string) context.Context {
+	return context.WithValue(ctx, locationIDContextKey, locationID)
+}
+
+// LocationIDFromContext returns the location ID added by WithLocationID
+func LocationIDFromContext(ctx context.Context) (string, bool) {
+	locationID, ok := ctx.Value(locationIDContextKey).(string)
+	return locationID, ok && locationID != ""
+}
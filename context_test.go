@@ -0,0 +1,34 @@
+package serverutils_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		with func(ctx context.Context, value string) context.Context
+		from func(ctx context.Context) (string, bool)
+	}{
+		{name: "flavour", with: serverutils.WithFlavour, from: serverutils.FlavourFromContext},
+		{name: "organization", with: serverutils.WithOrganizationID, from: serverutils.OrganizationIDFromContext},
+		{name: "location", with: serverutils.WithLocationID, from: serverutils.LocationIDFromContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := tt.from(context.Background())
+			assert.False(t, ok)
+
+			_, ok = tt.from(tt.with(context.Background(), ""))
+			assert.False(t, ok)
+
+			value, ok := tt.from(tt.with(context.Background(), "value"))
+			assert.True(t, ok)
+			assert.Equal(t, "value", value)
+		})
+	}
+}
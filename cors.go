@@ -0,0 +1,188 @@
+package serverutils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the CORS middleware
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to make cross origin requests.
+	// `*` allows any origin but without credentials; origins that may send credentials
+	// must be listed explicitly. No origin is allowed when it is empty.
+	AllowedOrigins []string
+
+	// AllowedHeaders are the request headers a cross origin request may carry
+	AllowedHeaders []string
+
+	// AllowedMethods are the methods a cross origin request may use
+	AllowedMethods []string
+
+	// ExposedHeaders are the response headers a browser may expose to the caller
+	ExposedHeaders []string
+
+	// AllowCredentials indicates whether cookies and auth headers may be sent
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// DefaultCORSOptions returns secure defaults suitable for GraphQL endpoints.
+//
+// No origin is allowed by default; the allowed origins must be configured explicitly.
+func DefaultCORSOptions() CORSOptions {
+	return CORSOptions{
+		AllowedOrigins:   []string{},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Requested-With"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+}
+
+// CORSOptionsFromEnv returns the default CORS options overridden by any of the
+// CORS environment variables that are set
+func CORSOptionsFromEnv() CORSOptions {
+	opts := DefaultCORSOptions()
+
+	if origins, err := GetEnvVar(CORSAllowedOriginsEnvVarName); err == nil {
+		opts.AllowedOrigins = splitCSV(origins)
+	}
+	if headers, err := GetEnvVar(CORSAllowedHeadersEnvVarName); err == nil {
+		opts.AllowedHeaders = splitCSV(headers)
+	}
+	if methods, err := GetEnvVar(CORSAllowedMethodsEnvVarName); err == nil {
+		opts.AllowedMethods = splitCSV(methods)
+	}
+	if maxAge, err := GetEnvVar(CORSMaxAgeEnvVarName); err == nil {
+		seconds, err := strconv.Atoi(maxAge)
+		if err == nil && seconds >= 0 {
+			opts.MaxAge = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return opts
+}
+
+// CORSMiddleware handles cross origin requests and preflight requests according to the
+// supplied options.
+//
+// Preflight requests are answered directly and are not passed to the next handler.
+// Requests from origins that are not allowed are passed on without any CORS headers,
+// so browsers will block them.
+func CORSMiddleware(opts CORSOptions) func(http.Handler) http.Handler {
+	allowedMethods := strings.Join(opts.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(opts.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(opts.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				origin := r.Header.Get("Origin")
+				w.Header().Add("Vary", "Origin")
+
+				if origin == "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+				allowOrigin, ok := opts.allowOrigin(origin)
+				if !ok {
+					if preflight {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				if preflight && (!opts.methodAllowed(r.Header.Get("Access-Control-Request-Method")) ||
+					!opts.headersAllowed(r.Header.Get("Access-Control-Request-Headers"))) {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+				if opts.AllowCredentials && allowOrigin != "*" {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+
+				if !preflight {
+					if exposedHeaders != "" {
+						w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+					}
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+				w.WriteHeader(http.StatusNoContent)
+			},
+		)
+	}
+}
+
+// allowOrigin returns the value of the Access-Control-Allow-Origin header for an origin.
+//
+// Origins that are listed explicitly are echoed back. Origins that are only allowed by `*`
+// get a literal `*` so that credentialed requests from any origin are never allowed.
+func (o CORSOptions) allowOrigin(origin string) (string, bool) {
+	wildcard := false
+	for _, allowed := range o.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+		if allowed == "*" {
+			wildcard = true
+		}
+	}
+	if wildcard {
+		return "*", true
+	}
+	return "", false
+}
+
+func (o CORSOptions) methodAllowed(method string) bool {
+	for _, allowed := range o.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o CORSOptions) headersAllowed(requested string) bool {
+	for _, header := range splitCSV(requested) {
+		found := false
+		for _, allowed := range o.AllowedHeaders {
+			if strings.EqualFold(allowed, header) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// splitCSV splits a comma separated value into its trimmed, non empty parts
+func splitCSV(value string) []string {
+	parts := []string{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
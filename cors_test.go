@@ -0,0 +1,164 @@
+package serverutils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSOptionsFromEnv(t *testing.T) {
+	os.Setenv(serverutils.CORSAllowedOriginsEnvVarName, "https://a.example.com, https://b.example.com")
+	os.Setenv(serverutils.CORSAllowedMethodsEnvVarName, "GET,POST")
+	os.Setenv(serverutils.CORSAllowedHeadersEnvVarName, "Authorization")
+	os.Setenv(serverutils.CORSMaxAgeEnvVarName, "60")
+	defer func() {
+		os.Unsetenv(serverutils.CORSAllowedOriginsEnvVarName)
+		os.Unsetenv(serverutils.CORSAllowedMethodsEnvVarName)
+		os.Unsetenv(serverutils.CORSAllowedHeadersEnvVarName)
+		os.Unsetenv(serverutils.CORSMaxAgeEnvVarName)
+	}()
+
+	opts := serverutils.CORSOptionsFromEnv()
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, opts.AllowedOrigins)
+	assert.Equal(t, []string{"GET", "POST"}, opts.AllowedMethods)
+	assert.Equal(t, []string{"Authorization"}, opts.AllowedHeaders)
+	assert.Equal(t, time.Minute, opts.MaxAge)
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	opts := serverutils.DefaultCORSOptions()
+	opts.AllowedOrigins = []string{"https://app.example.com"}
+	opts.ExposedHeaders = []string{"X-Request-ID"}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := serverutils.CORSMiddleware(opts)(next)
+
+	tests := []struct {
+		name            string
+		method          string
+		headers         map[string]string
+		wantStatus      int
+		wantAllowOrigin string
+		wantMaxAge      string
+	}{
+		{
+			name:       "same origin request",
+			method:     http.MethodPost,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:            "allowed origin",
+			method:          http.MethodPost,
+			headers:         map[string]string{"Origin": "https://app.example.com"},
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "https://app.example.com",
+		},
+		{
+			name:       "disallowed origin",
+			method:     http.MethodPost,
+			headers:    map[string]string{"Origin": "https://evil.example.com"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "allowed preflight",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "content-type, authorization",
+			},
+			wantStatus:      http.StatusNoContent,
+			wantAllowOrigin: "https://app.example.com",
+			wantMaxAge:      "600",
+		},
+		{
+			name:   "preflight with disallowed method",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "preflight with disallowed header",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "X-Custom",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "preflight from disallowed origin",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": http.MethodPost,
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, "/graphql", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			h.ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.wantStatus, rw.Code)
+			assert.Equal(t, tt.wantAllowOrigin, rw.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.wantMaxAge, rw.Header().Get("Access-Control-Max-Age"))
+		})
+	}
+}
+
+func TestCORSMiddleware_Wildcard(t *testing.T) {
+	opts := serverutils.DefaultCORSOptions()
+	opts.AllowedOrigins = []string{"*", "https://app.example.com"}
+
+	h := serverutils.CORSMiddleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name                string
+		origin              string
+		wantAllowOrigin     string
+		wantAllowCredential string
+	}{
+		{
+			name:                "listed origin may send credentials",
+			origin:              "https://app.example.com",
+			wantAllowOrigin:     "https://app.example.com",
+			wantAllowCredential: "true",
+		},
+		{
+			name:            "any other origin gets a wildcard without credentials",
+			origin:          "https://other.example.com",
+			wantAllowOrigin: "*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			req.Header.Set("Origin", tt.origin)
+			h.ServeHTTP(rw, req)
+
+			assert.Equal(t, http.StatusOK, rw.Code)
+			assert.Equal(t, tt.wantAllowOrigin, rw.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.wantAllowCredential, rw.Header().Get("Access-Control-Allow-Credentials"))
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package serverutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DataLoader defaults
+const (
+	// DefaultDataLoaderWait is how long a data loader waits for more keys before fetching a batch
+	DefaultDataLoaderWait = 2 * time.Millisecond
+
+	// DefaultDataLoaderMaxBatch is the maximum number of keys fetched in a batch
+	DefaultDataLoaderMaxBatch = 100
+)
+
+// BatchLoadFunc fetches the values of a batch of keys e.g with a single `IN` query.
+// Keys without a value are left out of the returned map.
+type BatchLoadFunc func(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+type dataLoaderBatch struct {
+	keys    []string
+	once    sync.Once
+	done    chan struct{}
+	results map[string]interface{}
+	err     error
+}
+
+// DataLoader batches and caches the lookups of GraphQL resolvers to avoid N+1 queries.
+//
+// Loads made within the wait duration of each other are fetched together. Values are cached
+// for the lifetime of the loader, so a loader should only serve a single request
+// (see DataLoaderMiddleware). Keys whose batch failed are not cached and are fetched again
+// by the next load.
+type DataLoader struct {
+	ctx      context.Context
+	load     BatchLoadFunc
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	cache   map[string]*dataLoaderBatch
+	pending *dataLoaderBatch
+}
+
+// NewDataLoader returns a data loader that fetches values with the supplied batch function.
+// The defaults are used when wait or maxBatch are not positive.
+//
+// Batches are fetched with ctx e.g the context of the request the loader serves, rather than
+// with the context of the caller that started the batch, so that a caller giving up does not
+// fail the loads of the other callers in the batch.
+func NewDataLoader(ctx context.Context, load BatchLoadFunc, wait time.Duration, maxBatch int) *DataLoader {
+	if wait <= 0 {
+		wait = DefaultDataLoaderWait
+	}
+	if maxBatch <= 0 {
+		maxBatch = DefaultDataLoaderMaxBatch
+	}
+	return &DataLoader{
+		ctx:      ctx,
+		load:     load,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    make(map[string]*dataLoaderBatch),
+	}
+}
+
+// Load returns the value of a key, or nil if the batch function did not return one
+func (l *DataLoader) Load(ctx context.Context, key string) (interface{}, error) {
+	l.mu.Lock()
+	batch, ok := l.cache[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &dataLoaderBatch{done: make(chan struct{})}
+			go l.fetchAfterWait(l.pending)
+		}
+		batch = l.pending
+		batch.keys = append(batch.keys, key)
+		l.cache[key] = batch
+
+		if len(batch.keys) >= l.maxBatch {
+			l.pending = nil
+			go l.fetch(batch)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-batch.done:
+	}
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	return batch.results[key], nil
+}
+
+// LoadAll returns the values of the supplied keys, in the same order
+func (l *DataLoader) LoadAll(ctx context.Context, keys []string) ([]interface{}, error) {
+	type result struct {
+		value interface{}
+		err   error
+	}
+	results := make([]result, len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			results[i].value, results[i].err = l.Load(ctx, key)
+		}(i, key)
+	}
+	wg.Wait()
+
+	values := make([]interface{}, len(keys))
+	for i, r := range results {
+		if r.err != nil {
+			return nil, r.err
+		}
+		values[i] = r.value
+	}
+	return values, nil
+}
+
+// Prime caches the value of a key e.g one that was fetched as part of another query.
+// Keys that are already cached are not changed.
+func (l *DataLoader) Prime(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	batch := &dataLoaderBatch{
+		keys:    []string{key},
+		done:    make(chan struct{}),
+		results: map[string]interface{}{key: value},
+	}
+	batch.once.Do(func() { close(batch.done) })
+	l.cache[key] = batch
+}
+
+func (l *DataLoader) fetchAfterWait(batch *dataLoaderBatch) {
+	time.Sleep(l.wait)
+
+	l.mu.Lock()
+	if l.pending == batch {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	// a no-op if the batch filled up and was fetched already
+	l.fetch(batch)
+}
+
+func (l *DataLoader) fetch(batch *dataLoaderBatch) {
+	batch.once.Do(func() {
+		defer close(batch.done)
+		defer func() {
+			if r := recover(); r != nil {
+				batch.err = fmt.Errorf("the batch load panicked: %v", r)
+			}
+			if batch.err != nil {
+				l.forget(batch)
+			}
+		}()
+		batch.results, batch.err = l.load(l.ctx, batch.keys)
+	})
+}
+
+// forget removes the keys of a failed batch from the cache so that they can be fetched again
+func (l *DataLoader) forget(batch *dataLoaderBatch) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range batch.keys {
+		if l.cache[key] == batch {
+			delete(l.cache, key)
+		}
+	}
+}
+
+// DataLoaderMiddleware adds a fresh data loader for each of the supplied batch functions to
+// the context of every request, so values are only cached for the duration of a request.
+// Resolvers get the loaders with DataLoaderFromContext.
+func DataLoaderMiddleware(loaders map[string]BatchLoadFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(WithDataLoaders(r.Context(), loaders)))
+			},
+		)
+	}
+}
+
+// WithDataLoaders returns a copy of the context with a fresh data loader for each of the
+// supplied batch functions e.g for work that is not triggered by a HTTP request.
+// The loaders fetch their batches with ctx.
+func WithDataLoaders(ctx context.Context, loaders map[string]BatchLoadFunc) context.Context {
+	requestLoaders := make(map[string]*DataLoader, len(loaders))
+	for name, load := range loaders {
+		requestLoaders[name] = NewDataLoader(ctx, load, DefaultDataLoaderWait, DefaultDataLoaderMaxBatch)
+	}
+	return context.WithValue(ctx, dataLoadersContextKey, requestLoaders)
+}
+
+// DataLoaderFromContext returns the named data loader added by DataLoaderMiddleware
+func DataLoaderFromContext(ctx context.Context, name string) (*DataLoader, error) {
+	loaders, ok := ctx.Value(dataLoadersContextKey).(map[string]*DataLoader)
+	if !ok {
+		return nil, fmt.Errorf("the context has no data loaders")
+	}
+	loader, ok := loaders[name]
+	if !ok {
+		return nil, fmt.Errorf("the context has no %s data loader", name)
+	}
+	return loader, nil
+}
@@ -0,0 +1,165 @@
+package serverutils_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingBatchLoader struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (l *recordingBatchLoader) load(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batch := append([]string{}, keys...)
+	sort.Strings(batch)
+	l.batches = append(l.batches, batch)
+
+	values := map[string]interface{}{}
+	for _, key := range keys {
+		if key != "missing" {
+			values[key] = "profile " + key
+		}
+	}
+	return values, nil
+}
+
+func TestDataLoader_Load(t *testing.T) {
+	ctx := context.Background()
+	loader := &recordingBatchLoader{}
+	dataLoader := serverutils.NewDataLoader(ctx, loader.load, 10*time.Millisecond, 0)
+
+	values, err := dataLoader.LoadAll(ctx, []string{"1", "2", "1", "missing"})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"profile 1", "profile 2", "profile 1", nil}, values)
+
+	// cached values are not fetched again
+	value, err := dataLoader.Load(ctx, "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "profile 2", value)
+
+	dataLoader.Prime("3", "primed")
+	value, err = dataLoader.Load(ctx, "3")
+	assert.Nil(t, err)
+	assert.Equal(t, "primed", value)
+
+	assert.Equal(t, [][]string{{"1", "2", "missing"}}, loader.batches)
+}
+
+func TestDataLoader_MaxBatch(t *testing.T) {
+	loader := &recordingBatchLoader{}
+	dataLoader := serverutils.NewDataLoader(context.Background(), loader.load, time.Second, 2)
+
+	start := time.Now()
+	_, err := dataLoader.LoadAll(context.Background(), []string{"1", "2"})
+	assert.Nil(t, err)
+
+	// full batches are fetched without waiting
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, 1, len(loader.batches))
+}
+
+func TestDataLoader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		load serverutils.BatchLoadFunc
+	}{
+		{
+			name: "error",
+			load: func(ctx context.Context, keys []string) (map[string]interface{}, error) {
+				return nil, fmt.Errorf("the database is unavailable")
+			},
+		},
+		{
+			name: "panic",
+			load: func(ctx context.Context, keys []string) (map[string]interface{}, error) {
+				panic("ka-boom")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataLoader := serverutils.NewDataLoader(context.Background(), tt.load, 0, 0)
+			_, err := dataLoader.Load(context.Background(), "1")
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestDataLoader_ErrorsAreNotCached(t *testing.T) {
+	calls := 0
+	dataLoader := serverutils.NewDataLoader(context.Background(), func(ctx context.Context, keys []string) (map[string]interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("the database is unavailable")
+		}
+		return map[string]interface{}{"1": "profile 1"}, nil
+	}, 0, 0)
+
+	_, err := dataLoader.Load(context.Background(), "1")
+	assert.NotNil(t, err)
+
+	value, err := dataLoader.Load(context.Background(), "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "profile 1", value)
+	assert.Equal(t, 2, calls)
+}
+
+func TestDataLoader_CallerCancelled(t *testing.T) {
+	dataLoader := serverutils.NewDataLoader(context.Background(), func(ctx context.Context, keys []string) (map[string]interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return map[string]interface{}{"1": "profile 1", "2": "profile 2"}, nil
+	}, 10*time.Millisecond, 0)
+
+	// the caller that starts the batch gives up before it is fetched
+	cancelled, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, _ = dataLoader.Load(cancelled, "1")
+	}()
+	time.Sleep(time.Millisecond)
+	cancel()
+
+	value, err := dataLoader.Load(context.Background(), "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "profile 2", value)
+}
+
+func TestDataLoaderMiddleware(t *testing.T) {
+	loader := &recordingBatchLoader{}
+	middleware := serverutils.DataLoaderMiddleware(map[string]serverutils.BatchLoadFunc{"profiles": loader.load})
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dataLoader, err := serverutils.DataLoaderFromContext(r.Context(), "profiles")
+		assert.Nil(t, err)
+		value, err := dataLoader.Load(r.Context(), "1")
+		assert.Nil(t, err)
+		assert.Equal(t, "profile 1", value)
+
+		_, err = serverutils.DataLoaderFromContext(r.Context(), "suppliers")
+		assert.NotNil(t, err)
+	}))
+
+	// every request gets a new loader, so nothing is cached across requests
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", nil))
+	}
+	assert.Equal(t, 2, len(loader.batches))
+
+	_, err := serverutils.DataLoaderFromContext(context.Background(), "profiles")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,273 @@
+package serverutils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Event consumer retry defaults
+const (
+	// DefaultEventMaxAttempts is the number of times an event is handled before it is dead-lettered
+	DefaultEventMaxAttempts = 5
+
+	// DefaultEventRetryBackoff is the delay before the first retry of a failed event; it doubles
+	// with every retry
+	DefaultEventRetryBackoff = 100 * time.Millisecond
+
+	// DefaultEventConcurrency is the number of events ConsumeEvents handles at a time
+	DefaultEventConcurrency = 10
+
+	// DeadLetterSaveTimeout is how long saving an event whose retries were interrupted by
+	// the end of the context may take
+	DeadLetterSaveTimeout = 10 * time.Second
+)
+
+// ErrDeadLetterNotFound is returned when a dead letter does not exist
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// EventHandlerFunc handles an event received from an event bus
+type EventHandlerFunc func(ctx context.Context, event Event) error
+
+// DeadLetter is an event that could not be handled, with the error of the last attempt
+type DeadLetter struct {
+	ID       string    `json:"id"`
+	Event    Event     `json:"event"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// DeadLetterStore keeps the events that consumers failed to handle so that they can be
+// inspected and requeued
+type DeadLetterStore interface {
+	Save(ctx context.Context, letter DeadLetter) error
+	Get(ctx context.Context, id string) (*DeadLetter, error)
+	List(ctx context.Context) ([]DeadLetter, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// RetryPolicy controls how WithDeadLetters retries failed events
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is supplied
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: DefaultEventMaxAttempts, Backoff: DefaultEventRetryBackoff}
+}
+
+// WithDeadLetters wraps an event handler so that failed events are retried with an exponential
+// backoff and saved to the dead-letter store once the policy's attempts are used up.
+//
+// A panic in the handler counts as a failed attempt. An event whose retries are interrupted
+// by the end of the context is dead-lettered straight away, using a context detached from
+// the done one, so that it is not lost. The wrapped handler only returns an error when the
+// event could not be dead-lettered, so callers can acknowledge an event whenever it returns nil.
+func WithDeadLetters(handler EventHandlerFunc, store DeadLetterStore, policy RetryPolicy) EventHandlerFunc {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultEventMaxAttempts
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = DefaultEventRetryBackoff
+	}
+
+	return func(ctx context.Context, event Event) error {
+		var err error
+		attempts := 0
+		for attempts < policy.MaxAttempts {
+			if attempts > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(policy.Backoff * time.Duration(1<<(attempts-1))):
+				}
+				if ctx.Err() != nil {
+					break
+				}
+			}
+
+			attempts++
+			err = handleEvent(ctx, handler, event)
+			if err == nil {
+				return nil
+			}
+		}
+
+		if ctx.Err() != nil {
+			// the event is saved even though the consumer is shutting down
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(context.Background(), DeadLetterSaveTimeout)
+			defer cancel()
+		}
+
+		letter := DeadLetter{
+			Event:    event,
+			Error:    err.Error(),
+			Attempts: attempts,
+			FailedAt: time.Now(),
+		}
+		letter.ID, err = newDeadLetterID()
+		if err != nil {
+			return err
+		}
+
+		Logger().WithFields(log.Fields{
+			"topic":       event.Topic,
+			"type":        event.Type,
+			"dead letter": letter.ID,
+			"error":       letter.Error,
+		}).Error("Dead-lettering an event that could not be handled")
+
+		err = store.Save(ctx, letter)
+		if err != nil {
+			return fmt.Errorf("unable to dead-letter the event: %w", err)
+		}
+		return nil
+	}
+}
+
+// handleEvent calls the handler, turning a panic into an error
+func handleEvent(ctx context.Context, handler EventHandlerFunc, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("the event handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, event)
+}
+
+// ConsumeEvents passes the events received on the channel to the handler until the channel is
+// closed or the context is done, then waits for the events being handled.
+//
+// Up to concurrency events (DefaultEventConcurrency when it is not positive) are handled at a
+// time, each in its own goroutine, so that slow handlers e.g those retrying with
+// WithDeadLetters keep receiving and are not evicted by the event bus; events may therefore be
+// handled out of order. Receiving pauses while that many events are being handled. Handler
+// panics are recovered and logged. A channel that is closed while the context is still active
+// means the subscription was evicted and is logged as an error, since events published in the
+// meantime were lost.
+func ConsumeEvents(ctx context.Context, events <-chan Event, handler EventHandlerFunc, concurrency int) {
+	if concurrency <= 0 {
+		concurrency = DefaultEventConcurrency
+	}
+	slots := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case slots <- struct{}{}:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() == nil {
+					Logger().Error("The event subscription ended before its context; events may have been dropped")
+				}
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				err := handleEvent(ctx, handler, event)
+				if err != nil {
+					Logger().WithFields(log.Fields{"topic": event.Topic, "error": err}).Error("Unable to handle an event")
+				}
+			}()
+		}
+	}
+}
+
+// RequeueDeadLetter publishes a dead-lettered event again and removes it from the store
+func RequeueDeadLetter(ctx context.Context, store DeadLetterStore, bus EventBus, id string) error {
+	letter, err := store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = bus.Publish(ctx, letter.Event)
+	if err != nil {
+		return fmt.Errorf("unable to requeue the dead letter %s: %w", id, err)
+	}
+	return store.Delete(ctx, id)
+}
+
+func newDeadLetterID() (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate a dead letter ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// MemoryDeadLetterStore is an in-memory DeadLetterStore for tests and single instance services
+type MemoryDeadLetterStore struct {
+	mu      sync.Mutex
+	letters map[string]DeadLetter
+}
+
+// NewMemoryDeadLetterStore returns an empty in-memory dead-letter store
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{letters: make(map[string]DeadLetter)}
+}
+
+// Save implements the DeadLetterStore interface
+func (s *MemoryDeadLetterStore) Save(ctx context.Context, letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters[letter.ID] = letter
+	return nil
+}
+
+// Get implements the DeadLetterStore interface
+func (s *MemoryDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letter, ok := s.letters[id]
+	if !ok {
+		return nil, ErrDeadLetterNotFound
+	}
+	return &letter, nil
+}
+
+// List implements the DeadLetterStore interface. The oldest letters are listed first.
+func (s *MemoryDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters := make([]DeadLetter, 0, len(s.letters))
+	for _, letter := range s.letters {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].FailedAt.Before(letters[j].FailedAt) })
+	return letters, nil
+}
+
+// Delete implements the DeadLetterStore interface
+func (s *MemoryDeadLetterStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.letters[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	delete(s.letters, id)
+	return nil
+}
@@ -0,0 +1,209 @@
+package serverutils_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	policy := serverutils.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	event := serverutils.Event{Topic: "engagement", Type: "FEED_ITEM_CREATED", Payload: "item-1"}
+
+	tests := []struct {
+		name            string
+		failures        int
+		wantAttempts    int
+		wantDeadLetters int
+	}{
+		{name: "succeeds", failures: 0, wantAttempts: 1},
+		{name: "succeeds on retry", failures: 2, wantAttempts: 3},
+		{name: "dead-lettered", failures: 5, wantAttempts: 3, wantDeadLetters: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := serverutils.NewMemoryDeadLetterStore()
+			attempts := 0
+			handler := serverutils.WithDeadLetters(func(ctx context.Context, event serverutils.Event) error {
+				attempts++
+				if attempts <= tt.failures {
+					return fmt.Errorf("attempt %d failed", attempts)
+				}
+				return nil
+			}, store, policy)
+
+			assert.Nil(t, handler(ctx, event))
+			assert.Equal(t, tt.wantAttempts, attempts)
+
+			letters, err := store.List(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantDeadLetters, len(letters))
+			if tt.wantDeadLetters > 0 {
+				assert.Equal(t, event, letters[0].Event)
+				assert.Equal(t, "attempt 3 failed", letters[0].Error)
+				assert.Equal(t, 3, letters[0].Attempts)
+			}
+		})
+	}
+}
+
+func TestWithDeadLetters_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	store := serverutils.NewMemoryDeadLetterStore()
+	handler := serverutils.WithDeadLetters(func(ctx context.Context, event serverutils.Event) error {
+		return fmt.Errorf("failed")
+	}, store, serverutils.DefaultRetryPolicy())
+
+	// the retries are interrupted but the event is dead-lettered rather than lost
+	assert.Nil(t, handler(ctx, serverutils.Event{Topic: "engagement"}))
+	letters, err := store.List(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, letters, 1)
+	assert.Equal(t, 1, letters[0].Attempts)
+	assert.Equal(t, "failed", letters[0].Error)
+}
+
+func TestRequeueDeadLetter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := serverutils.NewMemoryEventBus(1)
+	events, err := bus.Subscribe(ctx, "engagement")
+	assert.Nil(t, err)
+
+	store := serverutils.NewMemoryDeadLetterStore()
+	event := serverutils.Event{Topic: "engagement", Type: "FEED_ITEM_CREATED"}
+	assert.Nil(t, store.Save(ctx, serverutils.DeadLetter{ID: "1", Event: event}))
+
+	assert.Nil(t, serverutils.RequeueDeadLetter(ctx, store, bus, "1"))
+	received := <-events
+	assert.Equal(t, event.Type, received.Type)
+
+	_, err = store.Get(ctx, "1")
+	assert.Equal(t, serverutils.ErrDeadLetterNotFound, err)
+	assert.Equal(t, serverutils.ErrDeadLetterNotFound, serverutils.RequeueDeadLetter(ctx, store, bus, "1"))
+}
+
+func TestConsumeEvents(t *testing.T) {
+	events := make(chan serverutils.Event, 2)
+	events <- serverutils.Event{Type: "A"}
+	events <- serverutils.Event{Type: "B"}
+	close(events)
+
+	var mu sync.Mutex
+	handled := []string{}
+	serverutils.ConsumeEvents(context.Background(), events, func(ctx context.Context, event serverutils.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, event.Type)
+		return nil
+	}, 0)
+	sort.Strings(handled)
+	assert.Equal(t, []string{"A", "B"}, handled)
+}
+
+func TestConsumeEvents_Concurrency(t *testing.T) {
+	events := make(chan serverutils.Event, 10)
+	for i := 0; i < 10; i++ {
+		events <- serverutils.Event{Type: "A"}
+	}
+	close(events)
+
+	var mu sync.Mutex
+	running, maxRunning, handled := 0, 0, 0
+	serverutils.ConsumeEvents(context.Background(), events, func(ctx context.Context, event serverutils.Event) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		handled++
+		mu.Unlock()
+		return nil
+	}, 2)
+	assert.Equal(t, 10, handled)
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestConsumeEvents_SlowHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := serverutils.NewMemoryEventBus(1)
+	events, err := bus.Subscribe(ctx, "engagement")
+	assert.Nil(t, err)
+
+	store := serverutils.NewMemoryDeadLetterStore()
+	handled := make(chan string, 10)
+	handler := serverutils.WithDeadLetters(func(ctx context.Context, event serverutils.Event) error {
+		if event.Type == "FAILING" {
+			return fmt.Errorf("failed")
+		}
+		handled <- event.Type
+		return nil
+	}, store, serverutils.RetryPolicy{MaxAttempts: 3, Backoff: 20 * time.Millisecond})
+
+	done := make(chan struct{})
+	go func() {
+		serverutils.ConsumeEvents(ctx, events, handler, 0)
+		close(done)
+	}()
+
+	// the event being retried does not hold up the next ones, so the subscriber keeps up
+	assert.Nil(t, bus.Publish(ctx, serverutils.Event{Topic: "engagement", Type: "FAILING"}))
+	for i := 0; i < 3; i++ {
+		time.Sleep(5 * time.Millisecond)
+		assert.Nil(t, bus.Publish(ctx, serverutils.Event{Topic: "engagement", Type: "OK"}))
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-handled:
+		case <-time.After(time.Second):
+			t.Fatal("the events were not handled")
+		}
+	}
+	assert.Equal(t, 1, bus.Subscribers("engagement"))
+
+	// the failing event is dead-lettered once its retries are used up
+	deadline := time.Now().Add(time.Second)
+	for {
+		letters, err := store.List(context.Background())
+		assert.Nil(t, err)
+		if len(letters) == 1 || time.Now().After(deadline) {
+			assert.Equal(t, 1, len(letters))
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestWithDeadLetters_Panic(t *testing.T) {
+	store := serverutils.NewMemoryDeadLetterStore()
+	handler := serverutils.WithDeadLetters(func(ctx context.Context, event serverutils.Event) error {
+		panic("ka-boom")
+	}, store, serverutils.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond})
+
+	assert.Nil(t, handler(context.Background(), serverutils.Event{Topic: "engagement"}))
+	letters, err := store.List(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(letters))
+	assert.Contains(t, letters[0].Error, "ka-boom")
+}
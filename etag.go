@@ -0,0 +1,171 @@
+package serverutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ComputeETag returns a strong ETag for the supplied content e.g a serialized feed
+func ComputeETag(content []byte) string {
+	hash := sha256.Sum256(content)
+	return `"` + base64.RawURLEncoding.EncodeToString(hash[:16]) + `"`
+}
+
+// ETagMatches reports whether the If-None-Match header of the request matches the supplied
+// ETag, using the weak comparison that RFC 7232 requires for If-None-Match
+func ETagMatches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ConditionalGETMiddleware adds an ETag to the successful responses of GET and HEAD requests
+// and responds with 304 Not Modified when the client already has the current version.
+//
+// Responses are buffered to compute their ETag, so it suits JSON APIs rather than large
+// downloads. The supplied Cache-Control value e.g `private, no-cache` is set on responses
+// that don't have one. It should be placed inside GzipMiddleware so that the ETag is
+// computed from the uncompressed response.
+//
+// Upgrade requests e.g websockets are passed through untouched so that the connection can
+// be hijacked. Handlers that flush e.g server-sent events are streamed without an ETag from
+// the first flush onwards.
+func ConditionalGETMiddleware(cacheControl string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if (r.Method != http.MethodGet && r.Method != http.MethodHead) || r.Header.Get("Upgrade") != "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				bw := &bufferedResponseWriter{w: w, header: make(http.Header), status: http.StatusOK}
+				next.ServeHTTP(bw, r)
+				if bw.streaming {
+					return
+				}
+
+				bw.copyHeader()
+				if bw.status != http.StatusOK {
+					w.WriteHeader(bw.status)
+					writeBufferedBody(w, bw.body.Bytes())
+					return
+				}
+
+				etag := w.Header().Get("ETag")
+				if etag == "" {
+					etag = ComputeETag(bw.body.Bytes())
+					w.Header().Set("ETag", etag)
+				}
+				if cacheControl != "" && w.Header().Get("Cache-Control") == "" {
+					w.Header().Set("Cache-Control", cacheControl)
+				}
+
+				if ETagMatches(r, etag) {
+					w.Header().Del("Content-Type")
+					w.Header().Del("Content-Length")
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				writeBufferedBody(w, bw.body.Bytes())
+			},
+		)
+	}
+}
+
+func writeBufferedBody(w http.ResponseWriter, body []byte) {
+	_, err := w.Write(body)
+	if err != nil {
+		Logger().WithFields(log.Fields{"error": err}).Error("Unable to write response")
+	}
+}
+
+// bufferedResponseWriter holds a response so that it can be inspected before it is sent.
+//
+// Once the handler flushes, the held response is sent and the rest is streamed to w.
+type bufferedResponseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	streaming   bool
+}
+
+// copyHeader sets the held headers on w. Vary values are merged with those already set
+// e.g `Vary: Accept-Encoding` by GzipMiddleware.
+func (w *bufferedResponseWriter) copyHeader() {
+	for name, values := range w.header {
+		if name == "Vary" {
+			for _, value := range values {
+				if !headerHasToken(w.w.Header(), name, value) {
+					w.w.Header().Add(name, value)
+				}
+			}
+			continue
+		}
+		w.w.Header()[name] = values
+	}
+}
+
+// headerHasToken reports whether one of the comma separated values of a header is token
+func headerHasToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, candidate := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(token)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Flush sends the held response and streams the rest of it
+func (w *bufferedResponseWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		w.copyHeader()
+		w.w.WriteHeader(w.status)
+		writeBufferedBody(w.w, w.body.Bytes())
+		w.body.Reset()
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if w.streaming {
+		return w.w.Write(b)
+	}
+	return w.body.Write(b)
+}
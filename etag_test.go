@@ -0,0 +1,142 @@
+package serverutils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeETag(t *testing.T) {
+	assert.Equal(t, serverutils.ComputeETag([]byte("feed")), serverutils.ComputeETag([]byte("feed")))
+	assert.NotEqual(t, serverutils.ComputeETag([]byte("feed")), serverutils.ComputeETag([]byte("feed 2")))
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `"abc"`
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        bool
+	}{
+		{name: "no header", want: false},
+		{name: "match", ifNoneMatch: `"abc"`, want: true},
+		{name: "weak match", ifNoneMatch: `W/"abc"`, want: true},
+		{name: "one of many", ifNoneMatch: `"xyz", "abc"`, want: true},
+		{name: "wildcard", ifNoneMatch: `*`, want: true},
+		{name: "no match", ifNoneMatch: `"xyz"`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feed", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			assert.Equal(t, tt.want, serverutils.ETagMatches(req, etag))
+		})
+	}
+}
+
+func TestConditionalGETMiddleware(t *testing.T) {
+	feed := map[string]string{"items": "1, 2, 3"}
+	handler := serverutils.ConditionalGETMiddleware("private, no-cache")(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				serverutils.WriteJSONResponse(w, map[string]string{"error": "not found"}, http.StatusNotFound)
+				return
+			}
+			serverutils.WriteJSONResponse(w, feed, http.StatusOK)
+		},
+	))
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/feed", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, `{"items":"1, 2, 3"}`, rw.Body.String())
+	assert.Equal(t, "private, no-cache", rw.Header().Get("Cache-Control"))
+	etag := rw.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		etag       string
+		wantStatus int
+		wantETag   bool
+	}{
+		{name: "unchanged", method: http.MethodGet, path: "/feed", etag: etag, wantStatus: http.StatusNotModified, wantETag: true},
+		{name: "changed", method: http.MethodGet, path: "/feed", etag: `"stale"`, wantStatus: http.StatusOK, wantETag: true},
+		{name: "errors are not cached", method: http.MethodGet, path: "/missing", etag: etag, wantStatus: http.StatusNotFound},
+		{name: "not a GET", method: http.MethodPost, path: "/feed", etag: etag, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("If-None-Match", tt.etag)
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.wantStatus, rw.Code)
+			assert.Equal(t, tt.wantETag, rw.Header().Get("ETag") != "")
+			if tt.wantStatus == http.StatusNotModified {
+				assert.Empty(t, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestConditionalGETMiddleware_Upgrade(t *testing.T) {
+	handler := serverutils.ConditionalGETMiddleware("")(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// the original writer is passed through so that the connection can be hijacked
+			_, ok := w.(*httptest.ResponseRecorder)
+			assert.True(t, ok)
+			w.WriteHeader(http.StatusSwitchingProtocols)
+		},
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusSwitchingProtocols, rw.Code)
+	assert.Empty(t, rw.Header().Get("ETag"))
+}
+
+func TestConditionalGETMiddleware_Streaming(t *testing.T) {
+	handler := serverutils.ConditionalGETMiddleware("")(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("data: 1\n\n"))
+			assert.Nil(t, err)
+			w.(http.Flusher).Flush()
+			_, err = w.Write([]byte("data: 2\n\n"))
+			assert.Nil(t, err)
+		},
+	))
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.True(t, rw.Flushed)
+	assert.Equal(t, "data: 1\n\ndata: 2\n\n", rw.Body.String())
+	assert.Equal(t, "text/event-stream", rw.Header().Get("Content-Type"))
+	assert.Empty(t, rw.Header().Get("ETag"))
+}
+
+func TestConditionalGETMiddleware_MergesVary(t *testing.T) {
+	handler := serverutils.GzipMiddleware(0)(serverutils.ConditionalGETMiddleware("")(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+			w.Header().Add("Vary", "Accept-Encoding")
+			serverutils.WriteJSONResponse(w, map[string]string{"items": "1, 2, 3"}, http.StatusOK)
+		},
+	)))
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/feed", nil))
+	assert.Equal(t, []string{"Accept-Encoding", "Origin"}, rw.Header().Values("Vary"))
+}
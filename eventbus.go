@@ -0,0 +1,129 @@
+package serverutils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultEventBufferSize is the number of undelivered events buffered per subscriber
+const DefaultEventBufferSize = 32
+
+// Event is a message published on an event bus topic
+type Event struct {
+	Topic       string
+	Type        string
+	Payload     interface{}
+	PublishedAt time.Time
+}
+
+// EventBus delivers events published on a topic to the subscribers of the topic.
+//
+// Subscriptions end, and their channel is closed, when the subscriber's context is done.
+// This matches the lifecycle of GraphQL subscription resolvers.
+type EventBus interface {
+	Publish(ctx context.Context, event Event) error
+	Subscribe(ctx context.Context, topic string) (<-chan Event, error)
+}
+
+// UserTopic returns the topic of the events meant for a single user
+func UserTopic(uid string) string {
+	return fmt.Sprintf("user:%s", uid)
+}
+
+type eventSubscription struct {
+	events chan Event
+	closed bool
+}
+
+// MemoryEventBus is an in-process EventBus.
+//
+// Publishing never blocks: each subscriber has a buffer and subscribers that fall behind
+// by more than the buffer size are evicted i.e their channel is closed. Events are only
+// delivered to subscribers in the same instance of a service.
+type MemoryEventBus struct {
+	mu          sync.Mutex
+	bufferSize  int
+	subscribers map[string]map[*eventSubscription]struct{}
+}
+
+// NewMemoryEventBus returns an in-process event bus with the supplied per-subscriber buffer size
+func NewMemoryEventBus(bufferSize int) *MemoryEventBus {
+	if bufferSize <= 0 {
+		bufferSize = DefaultEventBufferSize
+	}
+	return &MemoryEventBus{
+		bufferSize:  bufferSize,
+		subscribers: make(map[string]map[*eventSubscription]struct{}),
+	}
+}
+
+// Subscribe implements the EventBus interface
+func (b *MemoryEventBus) Subscribe(ctx context.Context, topic string) (<-chan Event, error) {
+	if topic == "" {
+		return nil, fmt.Errorf("a topic is required")
+	}
+
+	subscription := &eventSubscription{events: make(chan Event, b.bufferSize)}
+
+	b.mu.Lock()
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[*eventSubscription]struct{})
+	}
+	b.subscribers[topic][subscription] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(topic, subscription)
+	}()
+
+	return subscription.events, nil
+}
+
+// Publish implements the EventBus interface
+func (b *MemoryEventBus) Publish(ctx context.Context, event Event) error {
+	if event.Topic == "" {
+		return fmt.Errorf("an event topic is required")
+	}
+	if event.PublishedAt.IsZero() {
+		event.PublishedAt = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscription := range b.subscribers[event.Topic] {
+		select {
+		case subscription.events <- event:
+		default:
+			Logger().Warnf("Evicting a slow subscriber of the %s topic", event.Topic)
+			b.remove(event.Topic, subscription)
+		}
+	}
+	return nil
+}
+
+// Subscribers returns the number of subscribers of a topic
+func (b *MemoryEventBus) Subscribers(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers[topic])
+}
+
+// remove must be called with the lock held
+func (b *MemoryEventBus) remove(topic string, subscription *eventSubscription) {
+	if subscription.closed {
+		return
+	}
+	subscription.closed = true
+	close(subscription.events)
+
+	delete(b.subscribers[topic], subscription)
+	if len(b.subscribers[topic]) == 0 {
+		delete(b.subscribers, topic)
+	}
+}
@@ -0,0 +1,70 @@
+package serverutils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryEventBus(t *testing.T) {
+	bus := serverutils.NewMemoryEventBus(2)
+	topic := serverutils.UserTopic("user-1")
+	assert.Equal(t, "user:user-1", topic)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := bus.Subscribe(ctx, topic)
+	assert.Nil(t, err)
+	other, err := bus.Subscribe(context.Background(), serverutils.UserTopic("user-2"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, bus.Subscribers(topic))
+
+	assert.Nil(t, bus.Publish(context.Background(), serverutils.Event{Topic: topic, Type: "ITEM_ADDED", Payload: "item-1"}))
+	event := <-events
+	assert.Equal(t, "ITEM_ADDED", event.Type)
+	assert.Equal(t, "item-1", event.Payload)
+	assert.False(t, event.PublishedAt.IsZero())
+
+	// events are only delivered to the subscribers of the topic
+	select {
+	case <-other:
+		t.Errorf("unexpected event on another topic")
+	default:
+	}
+
+	// the subscription ends when its context is done
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Errorf("the subscription was not closed")
+	}
+	assert.Equal(t, 0, bus.Subscribers(topic))
+
+	assert.NotNil(t, bus.Publish(context.Background(), serverutils.Event{}))
+	_, err = bus.Subscribe(context.Background(), "")
+	assert.NotNil(t, err)
+}
+
+func TestMemoryEventBus_SlowSubscriber(t *testing.T) {
+	bus := serverutils.NewMemoryEventBus(2)
+	topic := serverutils.UserTopic("user-1")
+
+	slow, err := bus.Subscribe(context.Background(), topic)
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, bus.Publish(context.Background(), serverutils.Event{Topic: topic, Type: "ITEM_MODIFIED"}))
+	}
+
+	// the buffered events are still delivered before the channel is closed
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Equal(t, 2, received)
+	assert.Equal(t, 0, bus.Subscribers(topic))
+}
@@ -0,0 +1,203 @@
+package serverutils
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ExportColumnTag is the struct tag that names the export column of a field e.g `export:"Phone Number"`.
+// Fields tagged `export:"-"` and unexported fields are not exported.
+const ExportColumnTag = "export"
+
+// ExportColumn is a column of a tabular export
+type ExportColumn struct {
+	Header string
+	Value  func(row interface{}) string
+}
+
+// ExportColumnsFromStruct returns a column for each exported field of the supplied struct
+// (or pointer to struct), in the order the fields are declared.
+//
+// Columns are named by the `export` tag of a field, or by the field name when it has no tag.
+// Times are formatted as RFC 3339 and nil pointers as empty cells.
+func ExportColumnsFromStruct(prototype interface{}) ([]ExportColumn, error) {
+	structType := reflect.TypeOf(prototype)
+	if structType != nil && structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("export columns can only be derived from a struct, not %T", prototype)
+	}
+
+	columns := []ExportColumn{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		header, ok := field.Tag.Lookup(ExportColumnTag)
+		if !field.IsExported() || header == "-" {
+			continue
+		}
+		if !ok || header == "" {
+			header = field.Name
+		}
+
+		index := i
+		columns = append(columns, ExportColumn{
+			Header: header,
+			Value: func(row interface{}) string {
+				value := reflect.Indirect(reflect.ValueOf(row))
+				if !value.IsValid() {
+					return ""
+				}
+				return formatExportValue(value.Field(index))
+			},
+		})
+	}
+	return columns, nil
+}
+
+func formatExportValue(value reflect.Value) string {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	switch v := value.Interface().(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// CSVExporter streams rows to a CSV writer, starting with a header row
+type CSVExporter struct {
+	writer      *csv.Writer
+	columns     []ExportColumn
+	wroteHeader bool
+}
+
+// NewCSVExporter returns an exporter that writes the supplied columns to w
+func NewCSVExporter(w io.Writer, columns []ExportColumn) *CSVExporter {
+	return &CSVExporter{writer: csv.NewWriter(w), columns: columns}
+}
+
+// Write writes a row, after the header row if it has not been written yet.
+//
+// Cells that spreadsheet applications would evaluate as formulas are escaped.
+func (e *CSVExporter) Write(row interface{}) error {
+	err := e.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		record[i] = escapeSpreadsheetFormula(column.Value(row))
+	}
+	err = e.writer.Write(record)
+	if err != nil {
+		return fmt.Errorf("unable to write a CSV row: %w", err)
+	}
+	return nil
+}
+
+// Flush writes any buffered rows, and the header row of empty exports
+func (e *CSVExporter) Flush() error {
+	err := e.writeHeader()
+	if err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *CSVExporter) writeHeader() error {
+	if e.wroteHeader {
+		return nil
+	}
+	headers := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		headers[i] = column.Header
+	}
+	err := e.writer.Write(headers)
+	if err != nil {
+		return fmt.Errorf("unable to write the CSV header: %w", err)
+	}
+	e.wroteHeader = true
+	return nil
+}
+
+// escapeSpreadsheetFormula prevents CSV injection: cells starting with =, +, -, @ or a
+// control character are prefixed with a quote unless they are numbers
+func escapeSpreadsheetFormula(cell string) string {
+	if cell == "" {
+		return cell
+	}
+	switch cell[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		if _, err := strconv.ParseFloat(cell, 64); err == nil {
+			return cell
+		}
+		return "'" + cell
+	}
+	return cell
+}
+
+// ExportRowsFunc produces the rows of an export by passing each of them to write.
+// It should stop and return the error when write fails.
+type ExportRowsFunc func(ctx context.Context, write func(row interface{}) error) error
+
+// CSVExportHandler returns a handler that streams the rows produced by rows as a CSV file
+// download with the supplied filename e.g `profiles.csv`
+func CSVExportHandler(filename string, columns []ExportColumn, rows ExportRowsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+		// rows are buffered by the CSV writer, so the download only starts once the buffer is
+		// flushed to the response
+		out := &countingWriter{w: w}
+		exporter := NewCSVExporter(out, columns)
+		err := rows(r.Context(), exporter.Write)
+		if err != nil {
+			Logger().WithFields(log.Fields{"export": filename, "error": err}).Error("Unable to export rows")
+			if out.written == 0 {
+				w.Header().Del("Content-Disposition")
+				WriteJSONResponse(w, ErrorMap(err), http.StatusInternalServerError)
+			}
+			// the download has started, the truncated file is all that can be sent
+			return
+		}
+
+		err = exporter.Flush()
+		if err != nil {
+			Logger().WithFields(log.Fields{"export": filename, "error": err}).Error("Unable to write the export")
+		}
+	}
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,140 @@
+package serverutils_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+type exportedProfile struct {
+	Name      string `export:"Full Name"`
+	Phone     string
+	Verified  *bool
+	CreatedAt time.Time `export:"Created At"`
+	PIN       string    `export:"-"`
+}
+
+func TestCSVExporter(t *testing.T) {
+	columns, err := serverutils.ExportColumnsFromStruct(exportedProfile{})
+	assert.Nil(t, err)
+
+	verified := true
+	rows := []*exportedProfile{
+		{Name: "Jane, Doe", Phone: "+254711223344", Verified: &verified, CreatedAt: time.Date(2023, 3, 14, 9, 30, 0, 0, time.UTC), PIN: "1234"},
+		{Name: "=HYPERLINK(\"http://example.com\")", Phone: "-12"},
+	}
+
+	var buf bytes.Buffer
+	exporter := serverutils.NewCSVExporter(&buf, columns)
+	for _, row := range rows {
+		assert.Nil(t, exporter.Write(row))
+	}
+	assert.Nil(t, exporter.Flush())
+
+	want := "Full Name,Phone,Verified,Created At\n" +
+		"\"Jane, Doe\",+254711223344,true,2023-03-14T09:30:00Z\n" +
+		"\"'=HYPERLINK(\"\"http://example.com\"\")\",-12,,\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestExportColumnsFromStruct_NotAStruct(t *testing.T) {
+	_, err := serverutils.ExportColumnsFromStruct("profile")
+	assert.NotNil(t, err)
+}
+
+func TestCSVExportHandler(t *testing.T) {
+	columns := []serverutils.ExportColumn{
+		{Header: "ID", Value: func(row interface{}) string { return fmt.Sprint(row) }},
+	}
+
+	tests := []struct {
+		name       string
+		rows       serverutils.ExportRowsFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "rows",
+			rows: func(ctx context.Context, write func(row interface{}) error) error {
+				for i := 1; i <= 2; i++ {
+					if err := write(i); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "ID\n1\n2\n",
+		},
+		{
+			name:       "no rows",
+			rows:       func(ctx context.Context, write func(row interface{}) error) error { return nil },
+			wantStatus: http.StatusOK,
+			wantBody:   "ID\n",
+		},
+		{
+			name: "error before the first row",
+			rows: func(ctx context.Context, write func(row interface{}) error) error {
+				return fmt.Errorf("the database is unavailable")
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"the database is unavailable"}`,
+		},
+		{
+			name: "error before the buffered rows are sent",
+			rows: func(ctx context.Context, write func(row interface{}) error) error {
+				if err := write(1); err != nil {
+					return err
+				}
+				return fmt.Errorf("the database is unavailable")
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"the database is unavailable"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler := serverutils.CSVExportHandler("profiles.csv", columns, tt.rows)
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/export", nil))
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, rr.Body.String())
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "attachment; filename=profiles.csv", rr.Header().Get("Content-Disposition"))
+				assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestCSVExportHandler_Truncated(t *testing.T) {
+	columns := []serverutils.ExportColumn{
+		{Header: "ID", Value: func(row interface{}) string { return fmt.Sprint(row) }},
+	}
+	handler := serverutils.CSVExportHandler("profiles.csv", columns, func(ctx context.Context, write func(row interface{}) error) error {
+		// enough rows to fill the CSV writer's buffer
+		for i := 0; i < 10000; i++ {
+			if err := write(i); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("the database is unavailable")
+	})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/export", nil))
+
+	// the download has started so the status can't be changed
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Body.String(), "ID\n0\n1\n"))
+	assert.NotContains(t, rr.Body.String(), "error")
+}
@@ -0,0 +1,181 @@
+package serverutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultFeatureFlagCacheTTL is how long flag definitions are cached by FeatureFlagService
+const DefaultFeatureFlagCacheTTL = time.Minute
+
+// FeatureFlag defines who a feature is enabled for.
+//
+// A disabled flag is off for everyone. An enabled flag is on for the users it targets: the
+// UIDs are always included, users must otherwise match the flavours and roles (when set) and
+// fall in the rollout percentage (when set). A plain `{Enabled: true}` flag is therefore on
+// for everyone.
+type FeatureFlag struct {
+	Name    string   `json:"name"`
+	Enabled bool     `json:"enabled"`
+	UIDs    []string `json:"uids,omitempty"`
+
+	// Flavours e.g PRO, CONSUMER the flag is limited to. All flavours when empty.
+	Flavours []string `json:"flavours,omitempty"`
+
+	// Roles the flag is limited to. All roles when empty.
+	Roles []string `json:"roles,omitempty"`
+
+	// Percentage of the matching users the flag is rolled out to, from 0 to 100. All the
+	// matching users when nil.
+	Percentage *int `json:"percentage,omitempty"`
+}
+
+// FeatureFlagUser is the user a flag is evaluated for
+type FeatureFlagUser struct {
+	UID     string
+	Flavour string
+	Roles   []string
+}
+
+// Evaluate reports whether the flag is on for the supplied user.
+//
+// Percentage rollouts are sticky: a user stays in (or out of) a rollout on every evaluation
+// and stays in when the percentage is increased.
+func (f FeatureFlag) Evaluate(user FeatureFlagUser) bool {
+	if !f.Enabled {
+		return false
+	}
+	if containsString(f.UIDs, user.UID) {
+		return true
+	}
+	if len(f.Flavours) > 0 && !containsString(f.Flavours, user.Flavour) {
+		return false
+	}
+	if len(f.Roles) > 0 {
+		matched := false
+		for _, role := range user.Roles {
+			if containsString(f.Roles, role) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.Percentage == nil {
+		return true
+	}
+	return rolloutBucket(f.Name, user.UID) < *f.Percentage
+}
+
+// rolloutBucket places a user in one of 100 buckets; every flag uses a different placement
+func rolloutBucket(flag, uid string) int {
+	hash := sha256.Sum256([]byte(flag + ":" + uid))
+	return int(binary.BigEndian.Uint64(hash[:8]) % 100)
+}
+
+// FeatureFlagStore keeps feature flag definitions.
+// GetFlag returns nil when there is no flag with the supplied name.
+type FeatureFlagStore interface {
+	GetFlag(ctx context.Context, name string) (*FeatureFlag, error)
+}
+
+type cachedFeatureFlag struct {
+	flag    *FeatureFlag
+	expires time.Time
+}
+
+// FeatureFlagService evaluates feature flags, caching their definitions
+type FeatureFlagService struct {
+	store FeatureFlagStore
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedFeatureFlag
+}
+
+// NewFeatureFlagService returns a feature flag service that caches flag definitions for the
+// supplied duration; DefaultFeatureFlagCacheTTL is used when it is not positive
+func NewFeatureFlagService(store FeatureFlagStore, ttl time.Duration) *FeatureFlagService {
+	if ttl <= 0 {
+		ttl = DefaultFeatureFlagCacheTTL
+	}
+	return &FeatureFlagService{
+		store: store,
+		ttl:   ttl,
+		cache: make(map[string]cachedFeatureFlag),
+	}
+}
+
+// IsEnabled reports whether the named flag is on for the supplied user.
+// Unknown flags are off.
+func (s *FeatureFlagService) IsEnabled(ctx context.Context, name string, user FeatureFlagUser) (bool, error) {
+	flag, err := s.flag(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	if flag == nil {
+		return false, nil
+	}
+	return flag.Evaluate(user), nil
+}
+
+func (s *FeatureFlagService) flag(ctx context.Context, name string) (*FeatureFlag, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[name]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.flag, nil
+	}
+
+	flag, err := s.store.GetFlag(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the %s feature flag: %w", name, err)
+	}
+
+	s.mu.Lock()
+	s.cache[name] = cachedFeatureFlag{flag: flag, expires: now.Add(s.ttl)}
+	s.mu.Unlock()
+	return flag, nil
+}
+
+// MemoryFeatureFlagStore is an in-memory FeatureFlagStore e.g for flags defined in code or tests
+type MemoryFeatureFlagStore struct {
+	mu    sync.RWMutex
+	flags map[string]FeatureFlag
+}
+
+// NewMemoryFeatureFlagStore returns an in-memory store holding the supplied flags
+func NewMemoryFeatureFlagStore(flags ...FeatureFlag) *MemoryFeatureFlagStore {
+	store := &MemoryFeatureFlagStore{flags: make(map[string]FeatureFlag)}
+	for _, flag := range flags {
+		store.flags[flag.Name] = flag
+	}
+	return store
+}
+
+// SetFlag adds or replaces a flag
+func (s *MemoryFeatureFlagStore) SetFlag(flag FeatureFlag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags[flag.Name] = flag
+}
+
+// GetFlag implements the FeatureFlagStore interface
+func (s *MemoryFeatureFlagStore) GetFlag(ctx context.Context, name string) (*FeatureFlag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flag, ok := s.flags[name]
+	if !ok {
+		return nil, nil
+	}
+	return &flag, nil
+}
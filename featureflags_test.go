@@ -0,0 +1,120 @@
+package serverutils_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func percentage(p int) *int {
+	return &p
+}
+
+func TestFeatureFlag_Evaluate(t *testing.T) {
+	pro := serverutils.FeatureFlagUser{UID: "user-1", Flavour: "PRO", Roles: []string{"EMPLOYEE"}}
+	consumer := serverutils.FeatureFlagUser{UID: "user-2", Flavour: "CONSUMER"}
+
+	tests := []struct {
+		name string
+		flag serverutils.FeatureFlag
+		user serverutils.FeatureFlagUser
+		want bool
+	}{
+		{name: "disabled", flag: serverutils.FeatureFlag{Name: "f", Percentage: percentage(100)}, user: pro, want: false},
+		{name: "everyone", flag: serverutils.FeatureFlag{Name: "f", Enabled: true, Percentage: percentage(100)}, user: consumer, want: true},
+		{name: "nobody", flag: serverutils.FeatureFlag{Name: "f", Enabled: true, Percentage: percentage(0)}, user: consumer, want: false},
+		{name: "boolean", flag: serverutils.FeatureFlag{Name: "f", Enabled: true}, user: consumer, want: true},
+		{name: "targeted UID", flag: serverutils.FeatureFlag{Name: "f", Enabled: true, UIDs: []string{"user-2"}, Percentage: percentage(0)}, user: consumer, want: true},
+		{
+			name: "flavour matches",
+			flag: serverutils.FeatureFlag{Name: "f", Enabled: true, Flavours: []string{"PRO"}, Percentage: percentage(100)},
+			user: pro,
+			want: true,
+		},
+		{
+			name: "flavour targeted without a percentage",
+			flag: serverutils.FeatureFlag{Name: "f", Enabled: true, Flavours: []string{"PRO"}},
+			user: pro,
+			want: true,
+		},
+		{
+			name: "flavour does not match",
+			flag: serverutils.FeatureFlag{Name: "f", Enabled: true, Flavours: []string{"PRO"}, Percentage: percentage(100)},
+			user: consumer,
+			want: false,
+		},
+		{
+			name: "role matches",
+			flag: serverutils.FeatureFlag{Name: "f", Enabled: true, Roles: []string{"AGENT", "EMPLOYEE"}, Percentage: percentage(100)},
+			user: pro,
+			want: true,
+		},
+		{
+			name: "role does not match",
+			flag: serverutils.FeatureFlag{Name: "f", Enabled: true, Roles: []string{"AGENT"}, Percentage: percentage(100)},
+			user: pro,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.flag.Evaluate(tt.user))
+		})
+	}
+}
+
+func TestFeatureFlag_Evaluate_Percentage(t *testing.T) {
+	half := serverutils.FeatureFlag{Name: "new-feed", Enabled: true, Percentage: percentage(50)}
+	more := serverutils.FeatureFlag{Name: "new-feed", Enabled: true, Percentage: percentage(80)}
+
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		user := serverutils.FeatureFlagUser{UID: fmt.Sprintf("user-%d", i)}
+		if half.Evaluate(user) {
+			enabled++
+			// increasing the rollout keeps the users already in it
+			assert.True(t, more.Evaluate(user))
+		}
+		// the rollout is sticky
+		assert.Equal(t, half.Evaluate(user), half.Evaluate(user))
+	}
+	assert.True(t, enabled > 400 && enabled < 600)
+}
+
+type countingFlagStore struct {
+	*serverutils.MemoryFeatureFlagStore
+	calls int
+}
+
+func (s *countingFlagStore) GetFlag(ctx context.Context, name string) (*serverutils.FeatureFlag, error) {
+	s.calls++
+	return s.MemoryFeatureFlagStore.GetFlag(ctx, name)
+}
+
+func TestFeatureFlagService_IsEnabled(t *testing.T) {
+	ctx := context.Background()
+	store := &countingFlagStore{MemoryFeatureFlagStore: serverutils.NewMemoryFeatureFlagStore(
+		serverutils.FeatureFlag{Name: "experiments", Enabled: true, Percentage: percentage(100)},
+	)}
+	service := serverutils.NewFeatureFlagService(store, time.Minute)
+	user := serverutils.FeatureFlagUser{UID: "user-1"}
+
+	enabled, err := service.IsEnabled(ctx, "experiments", user)
+	assert.Nil(t, err)
+	assert.True(t, enabled)
+
+	// the definition is cached
+	store.SetFlag(serverutils.FeatureFlag{Name: "experiments"})
+	enabled, err = service.IsEnabled(ctx, "experiments", user)
+	assert.Nil(t, err)
+	assert.True(t, enabled)
+	assert.Equal(t, 1, store.calls)
+
+	enabled, err = service.IsEnabled(ctx, "unknown", user)
+	assert.Nil(t, err)
+	assert.False(t, enabled)
+}
@@ -0,0 +1,103 @@
+package serverutils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FlavourHeader is the header that apps send their flavour in
+const FlavourHeader = "X-Flavour"
+
+// App flavours
+const (
+	// FlavourPro is the flavour of the apps used by professionals e.g agents and employees
+	FlavourPro = "PRO"
+
+	// FlavourConsumer is the flavour of the apps used by consumers
+	FlavourConsumer = "CONSUMER"
+)
+
+// FlavourMiddleware adds the flavour of the app that made a request to the request context
+// (see FlavourFromContext).
+//
+// The flavour is read from the X-Flavour header, or from the token verified by a preceding
+// JWTMiddleware. Requests without a flavour, with a flavour that is not allowed or with a header
+// that contradicts the token are rejected with a 400 status. PRO and CONSUMER are allowed when no
+// flavours are supplied. Flavours are not case sensitive.
+//
+// The X-Flavour header is supplied by the client, so the flavour in the context must only be used
+// for routing and presentation. Use RequireFlavour to restrict access by flavour.
+func FlavourMiddleware(allowedFlavours ...string) func(http.Handler) http.Handler {
+	if len(allowedFlavours) == 0 {
+		allowedFlavours = []string{FlavourPro, FlavourConsumer}
+	}
+	allowedFlavours = normalizeFlavours(allowedFlavours)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				flavour := strings.ToUpper(strings.TrimSpace(r.Header.Get(FlavourHeader)))
+
+				if claimed, ok := FlavourFromContext(r.Context()); ok {
+					if flavour != "" && flavour != strings.ToUpper(claimed) {
+						err := fmt.Errorf("the %s flavour does not match the %s flavour of the token", flavour, claimed)
+						WriteJSONResponse(w, ErrorMap(err), http.StatusBadRequest)
+						return
+					}
+					flavour = strings.ToUpper(claimed)
+				}
+
+				if flavour == "" {
+					err := fmt.Errorf("the %s header is required", FlavourHeader)
+					WriteJSONResponse(w, ErrorMap(err), http.StatusBadRequest)
+					return
+				}
+				if !containsString(allowedFlavours, flavour) {
+					err := fmt.Errorf("%s is not a valid flavour", flavour)
+					WriteJSONResponse(w, ErrorMap(err), http.StatusBadRequest)
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(WithFlavour(r.Context(), flavour)))
+			},
+		)
+	}
+}
+
+// RequireFlavour restricts an endpoint to apps of the supplied flavours.
+//
+// The flavour is read from the token verified by a preceding JWTMiddleware and never from the
+// X-Flavour header, which the client controls. Requests without a verified token are rejected
+// with a 401 status while tokens of other flavours are rejected with a 403 status.
+func RequireFlavour(flavours ...string) func(http.Handler) http.Handler {
+	flavours = normalizeFlavours(flavours)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				claims, ok := JWTClaimsFromContext(r.Context())
+				if !ok {
+					err := fmt.Errorf("a verified token is required")
+					WriteJSONResponse(w, ErrorMap(err), http.StatusUnauthorized)
+					return
+				}
+				flavour := strings.ToUpper(strings.TrimSpace(claims.Flavour))
+				if !containsString(flavours, flavour) {
+					err := fmt.Errorf("this endpoint is not available to %s apps", flavour)
+					WriteJSONResponse(w, ErrorMap(err), http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+func normalizeFlavours(flavours []string) []string {
+	normalized := make([]string, 0, len(flavours))
+	for _, flavour := range flavours {
+		normalized = append(normalized, strings.ToUpper(strings.TrimSpace(flavour)))
+	}
+	return normalized
+}
@@ -0,0 +1,98 @@
+package serverutils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlavourMiddleware(t *testing.T) {
+	handler := serverutils.FlavourMiddleware("pro", "Consumer")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			flavour, ok := serverutils.FlavourFromContext(r.Context())
+			assert.True(t, ok)
+			assert.Equal(t, serverutils.FlavourPro, flavour)
+		}),
+	)
+
+	tests := []struct {
+		name         string
+		header       string
+		tokenFlavour string
+		wantStatus   int
+	}{
+		{name: "header", header: "PRO", wantStatus: http.StatusOK},
+		{name: "lower case header", header: "pro", wantStatus: http.StatusOK},
+		{name: "token", tokenFlavour: "PRO", wantStatus: http.StatusOK},
+		{name: "header matches token", header: "PRO", tokenFlavour: "PRO", wantStatus: http.StatusOK},
+		{name: "header contradicts token", header: "PRO", tokenFlavour: "CONSUMER", wantStatus: http.StatusBadRequest},
+		{name: "missing", wantStatus: http.StatusBadRequest},
+		{name: "unknown", header: "ADMIN", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(serverutils.FlavourHeader, tt.header)
+			}
+			if tt.tokenFlavour != "" {
+				req = req.WithContext(serverutils.WithFlavour(req.Context(), tt.tokenFlavour))
+			}
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+			assert.Equal(t, tt.wantStatus, rw.Code)
+		})
+	}
+}
+
+func TestRequireFlavour(t *testing.T) {
+	key, err := serverutils.NewHMACJWTKey([]byte("secret"))
+	assert.Nil(t, err)
+
+	handler := serverutils.JWTMiddleware(key, "api")(serverutils.FlavourMiddleware()(serverutils.RequireFlavour("pro")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)))
+
+	tests := []struct {
+		name         string
+		header       string
+		tokenFlavour string
+		wantStatus   int
+	}{
+		{name: "token", tokenFlavour: "PRO", wantStatus: http.StatusOK},
+		{name: "lower case token", tokenFlavour: "pro", wantStatus: http.StatusOK},
+		{name: "restricted flavour", tokenFlavour: "CONSUMER", wantStatus: http.StatusForbidden},
+		{name: "token without a flavour", header: "PRO", tokenFlavour: "", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := key.IssueToken(serverutils.JWTClaims{Audience: "api", UID: "user", Flavour: tt.tokenFlavour}, time.Minute)
+			assert.Nil(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.header != "" {
+				req.Header.Set(serverutils.FlavourHeader, tt.header)
+			}
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+			assert.Equal(t, tt.wantStatus, rw.Code)
+		})
+	}
+}
+
+func TestRequireFlavour_HeaderOnly(t *testing.T) {
+	handler := serverutils.FlavourMiddleware()(serverutils.RequireFlavour(serverutils.FlavourPro)(http.NotFoundHandler()))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(serverutils.FlavourHeader, serverutils.FlavourPro)
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+}
@@ -0,0 +1,58 @@
+package serverutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GraphQLRequest is the body of a GraphQL-over-HTTP request
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLError is an error in a GraphQL response
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLResponse is the decoded body of a GraphQL-over-HTTP response
+type GraphQLResponse struct {
+	StatusCode int             `json:"-"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	Errors     []GraphQLError  `json:"errors,omitempty"`
+}
+
+// DecodeData decodes the data of a GraphQL response into the supplied value
+func (r GraphQLResponse) DecodeData(target interface{}) error {
+	if len(r.Data) == 0 {
+		return fmt.Errorf("the GraphQL response has no data")
+	}
+	return json.Unmarshal(r.Data, target)
+}
+
+// BuildGraphQLRequest composes a GraphQL-over-HTTP POST request to `/graphql`.
+//
+// When a token is supplied, it is sent as a bearer token.
+func BuildGraphQLRequest(query string, variables map[string]interface{}, token string) (*http.Request, error) {
+	body, err := json.Marshal(GraphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal the GraphQL request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/graphql", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to compose the GraphQL request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
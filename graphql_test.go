@@ -0,0 +1,40 @@
+package serverutils_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildGraphQLRequest(t *testing.T) {
+	req, err := serverutils.BuildGraphQLRequest(`query { listPayers { name } }`, map[string]interface{}{"first": 10}, "a-token")
+	assert.Nil(t, err)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/graphql", req.URL.Path)
+	assert.Equal(t, "Bearer a-token", req.Header.Get("Authorization"))
+
+	body, err := io.ReadAll(req.Body)
+	assert.Nil(t, err)
+	decoded := serverutils.GraphQLRequest{}
+	assert.Nil(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, float64(10), decoded.Variables["first"])
+
+	req, err = serverutils.BuildGraphQLRequest(`query { listPayers { name } }`, nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "", req.Header.Get("Authorization"))
+
+	_, err = serverutils.BuildGraphQLRequest(`query { listPayers { name } }`, map[string]interface{}{"bad": make(chan int)}, "")
+	assert.NotNil(t, err)
+}
+
+func TestGraphQLResponse_DecodeData(t *testing.T) {
+	data := map[string]string{}
+	assert.Nil(t, serverutils.GraphQLResponse{Data: json.RawMessage(`{"name":"Jubilee"}`)}.DecodeData(&data))
+	assert.Equal(t, "Jubilee", data["name"])
+
+	assert.NotNil(t, serverutils.GraphQLResponse{}.DecodeData(&data))
+}
@@ -0,0 +1,125 @@
+package serverutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health statuses reported by the health check and readiness handlers
+const (
+	HealthStatusUp   = "UP"
+	HealthStatusDown = "DOWN"
+)
+
+// DefaultHealthCheckTimeout is the maximum amount of time the health checks
+// of a single probe are allowed to run for
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthCheck checks whether a dependency of the service e.g a database or an
+// upstream API is usable. It should return a non nil error if it is not.
+type HealthCheck func(ctx context.Context) error
+
+// HealthCheckResult is the outcome of a single health check
+type HealthCheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthReport is the structured JSON status returned by the health check and
+// readiness handlers
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// RunHealthChecks runs the supplied checks concurrently and aggregates their results.
+//
+// The overall status is DOWN if any of the checks fails.
+func RunHealthChecks(ctx context.Context, checks map[string]HealthCheck) HealthReport {
+	report := HealthReport{
+		Status: HealthStatusUp,
+		Checks: make(map[string]HealthCheckResult, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+
+			result := HealthCheckResult{Status: HealthStatusUp}
+			if err := check(ctx); err != nil {
+				result = HealthCheckResult{Status: HealthStatusDown, Error: err.Error()}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status == HealthStatusDown {
+				report.Status = HealthStatusDown
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// HealthCheckHandler returns a liveness handler that runs the supplied checks and
+// responds with a structured JSON status.
+//
+// It responds with a 200 status when all checks pass and a 503 status otherwise.
+// When no checks are supplied, it reports that the server is up.
+func HealthCheckHandler(checks map[string]HealthCheck) http.HandlerFunc {
+	return ReadinessHandler(DefaultHealthCheckTimeout, checks)
+}
+
+// ReadinessHandler returns a readiness handler that runs the supplied checks within
+// the supplied timeout and responds with a structured JSON status.
+//
+// It responds with a 200 status when all checks pass and a 503 status otherwise.
+func ReadinessHandler(timeout time.Duration, checks map[string]HealthCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		report := RunHealthChecks(ctx, checks)
+
+		status := http.StatusOK
+		if report.Status != HealthStatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		WriteJSONResponse(w, report, status)
+	}
+}
+
+// URLReachableCheck returns a health check that passes when the supplied URL responds
+// without a server error e.g to check that a schema registry or upstream API is reachable
+func URLReachableCheck(url string) HealthCheck {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("unable to compose request to %s: %w", url, err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("%s is not reachable: %w", url, err)
+		}
+		defer func() {
+			err := resp.Body.Close()
+			if err != nil {
+				Logger().Println(err)
+			}
+		}()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package serverutils_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheckHandler(t *testing.T) {
+	passing := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return fmt.Errorf("firestore unreachable") }
+
+	tests := []struct {
+		name       string
+		checks     map[string]serverutils.HealthCheck
+		wantStatus int
+		wantReport string
+	}{
+		{
+			name:       "no checks",
+			checks:     nil,
+			wantStatus: http.StatusOK,
+			wantReport: serverutils.HealthStatusUp,
+		},
+		{
+			name: "all checks pass",
+			checks: map[string]serverutils.HealthCheck{
+				"firebase":  passing,
+				"firestore": passing,
+			},
+			wantStatus: http.StatusOK,
+			wantReport: serverutils.HealthStatusUp,
+		},
+		{
+			name: "a check fails",
+			checks: map[string]serverutils.HealthCheck{
+				"firebase":  passing,
+				"firestore": failing,
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: serverutils.HealthStatusDown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			serverutils.HealthCheckHandler(tt.checks).ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.wantStatus, rw.Code)
+
+			var report serverutils.HealthReport
+			err := json.NewDecoder(rw.Body).Decode(&report)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantReport, report.Status)
+			assert.Len(t, report.Checks, len(tt.checks))
+		})
+	}
+}
+
+func TestReadinessHandler_Timeout(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	h := serverutils.ReadinessHandler(10*time.Millisecond, map[string]serverutils.HealthCheck{"slow": slow})
+	h.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+}
+
+func TestURLReachableCheck(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{
+			name:    "reachable",
+			url:     up.URL,
+			wantErr: false,
+		},
+		{
+			name:    "server error",
+			url:     down.URL,
+			wantErr: true,
+		},
+		{
+			name:    "unreachable",
+			url:     "http://127.0.0.1:0",
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			url:     "://invalid",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := serverutils.URLReachableCheck(tt.url)(context.Background())
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
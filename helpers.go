@@ -2,7 +2,6 @@ package serverutils
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -83,8 +82,9 @@ func MustGetEnvVar(envVarName string) string {
 	val, err := GetEnvVar(envVarName)
 	if err != nil {
 		msg := fmt.Sprintf("mandatory environment variable %s not found", envVarName)
-		log.Panicf(msg)
-		os.Exit(1)
+		// the panic does not depend on the logger's configuration e.g a test hook
+		Logger().Error(msg)
+		panic(msg)
 	}
 	return val
 }
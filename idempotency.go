@@ -0,0 +1,294 @@
+package serverutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// IdempotencyKeyHeader is the header clients use to make a mutating request idempotent.
+// Clients should send a new random value e.g a UUID for every distinct operation.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses that are replays of a stored response
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// DefaultIdempotencyTTL is how long stored responses are kept when no TTL is supplied
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotentResponse is the response stored against an idempotency key.
+//
+// A response whose StatusCode is zero belongs to a request that is still being processed.
+type IdempotentResponse struct {
+	RequestHash string      `json:"requestHash"`
+	StatusCode  int         `json:"statusCode"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// Completed reports whether the request that the response belongs to has been processed
+func (r *IdempotentResponse) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// IdempotencyStore keeps the responses of idempotent requests.
+//
+// Start atomically reserves the key for a request with the supplied hash and returns nil.
+// If the key is already known, the stored response is returned instead and nothing is reserved.
+// Complete stores the response of a reserved key while Release drops the reservation so that
+// the request can be retried.
+type IdempotencyStore interface {
+	Start(ctx context.Context, key string, requestHash string, ttl time.Duration) (*IdempotentResponse, error)
+	Complete(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyMiddleware replays the stored response of POST, PUT, PATCH and DELETE requests
+// that are retried with the same Idempotency-Key header.
+//
+// Keys are scoped to the caller identified by JWTMiddleware or VerifyInterserviceJWT so
+// that one caller can't replay another's response by reusing their key. Request bodies larger
+// than DefaultMaxBodyBytes are rejected with a 413 status.
+//
+// Requests without the header are processed as usual. Reusing a key for a different request
+// is rejected with a 422 status while retrying a request that is still being processed is
+// rejected with a 409 status. Server errors (5xx) are not stored so that they can be retried.
+//
+// If the store fails, the request is processed as usual so that a store outage does not take
+// down the service.
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				key := r.Header.Get(IdempotencyKeyHeader)
+				if key == "" || !isMutatingMethod(r.Method) {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, DefaultMaxBodyBytes))
+				if err != nil {
+					var maxBytesError *http.MaxBytesError
+					if errors.As(err, &maxBytesError) {
+						WriteJSONResponse(w, ErrorMap(fmt.Errorf("request body must not be larger than %d bytes", maxBytesError.Limit)), http.StatusRequestEntityTooLarge)
+						return
+					}
+					WriteJSONResponse(w, ErrorMap(fmt.Errorf("unable to read request body: %w", err)), http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewBuffer(body))
+				hash := idempotencyRequestHash(r, body)
+				key = idempotencyStoreKey(r.Context(), key)
+
+				ctx := r.Context()
+				stored, err := store.Start(ctx, key, hash, ttl)
+				if err != nil {
+					Logger().WithFields(log.Fields{"error": err}).Error("Unable to check the idempotency key")
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				if stored != nil {
+					writeStoredResponse(w, stored, hash)
+					return
+				}
+
+				// release the key if the handler panics so that the request can be retried
+				completed := false
+				defer func() {
+					if completed {
+						return
+					}
+					if err := store.Release(ctx, key); err != nil {
+						Logger().WithFields(log.Fields{"error": err}).Error("Unable to release the idempotency key")
+					}
+				}()
+
+				recorder := &idempotencyRecorder{ResponseWriter: w}
+				next.ServeHTTP(recorder, r)
+				completed = true
+
+				if recorder.statusCode() >= http.StatusInternalServerError {
+					err = store.Release(ctx, key)
+				} else {
+					err = store.Complete(ctx, key, IdempotentResponse{
+						RequestHash: hash,
+						StatusCode:  recorder.statusCode(),
+						Header:      w.Header().Clone(),
+						Body:        recorder.body.Bytes(),
+					}, ttl)
+				}
+				if err != nil {
+					Logger().WithFields(log.Fields{"error": err}).Error("Unable to save the idempotent response")
+				}
+			},
+		)
+	}
+}
+
+func writeStoredResponse(w http.ResponseWriter, stored *IdempotentResponse, hash string) {
+	if stored.RequestHash != hash {
+		WriteJSONResponse(w, ErrorMap(fmt.Errorf("the %s has been used for a different request", IdempotencyKeyHeader)), http.StatusUnprocessableEntity)
+		return
+	}
+	if !stored.Completed() {
+		WriteJSONResponse(w, ErrorMap(fmt.Errorf("a request with the same %s is being processed", IdempotencyKeyHeader)), http.StatusConflict)
+		return
+	}
+
+	for name, values := range stored.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(stored.StatusCode)
+	_, err := w.Write(stored.Body)
+	if err != nil {
+		Logger().WithFields(log.Fields{"error": err}).Error("Unable to write the idempotent response")
+	}
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// idempotencyStoreKey scopes an idempotency key to the user or service that made the request
+func idempotencyStoreKey(ctx context.Context, key string) string {
+	if claims, ok := JWTClaimsFromContext(ctx); ok {
+		return fmt.Sprintf("user:%s:%s", claims.UID, key)
+	}
+	if claims, ok := InterserviceClaimsFromContext(ctx); ok {
+		return fmt.Sprintf("service:%s:%s", claims.Issuer, key)
+	}
+	return "anonymous:" + key
+}
+
+// idempotencyRequestHash identifies a request by its method, path and body
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyRecorder passes the response through to the client while keeping a copy
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *idempotencyRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+type idempotencyRecord struct {
+	response IdempotentResponse
+	expires  time.Time
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore.
+//
+// It is suitable for a single instance of a service; responses are not shared across instances.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*idempotencyRecord
+	calls   int
+}
+
+// NewMemoryIdempotencyStore returns an initialized in-memory idempotency store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]*idempotencyRecord),
+	}
+}
+
+// number of calls to Start between sweeps of expired records
+const idempotencySweepInterval = 1000
+
+// Start implements the IdempotencyStore interface
+func (s *MemoryIdempotencyStore) Start(ctx context.Context, key string, requestHash string, ttl time.Duration) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.calls++
+	if s.calls%idempotencySweepInterval == 0 {
+		s.sweep(now)
+	}
+
+	record, ok := s.records[key]
+	if ok && now.Before(record.expires) {
+		response := record.response
+		return &response, nil
+	}
+
+	s.records[key] = &idempotencyRecord{
+		response: IdempotentResponse{RequestHash: requestHash},
+		expires:  now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// Complete implements the IdempotencyStore interface
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = &idempotencyRecord{
+		response: response,
+		expires:  time.Now().Add(ttl),
+	}
+	return nil
+}
+
+// Release implements the IdempotencyStore interface
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	for key, record := range s.records {
+		if !now.Before(record.expires) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package serverutils_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	calls := 0
+	handler := serverutils.IdempotencyMiddleware(serverutils.NewMemoryIdempotencyStore(), time.Minute)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if r.URL.Path == "/fail" {
+				serverutils.WriteJSONResponse(w, serverutils.ErrorMap(fmt.Errorf("failed")), http.StatusInternalServerError)
+				return
+			}
+			serverutils.WriteJSONResponse(w, map[string]int{"calls": calls}, http.StatusCreated)
+		}),
+	)
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(serverutils.IdempotencyKeyHeader, key)
+		}
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+		return rw
+	}
+
+	first := do(http.MethodPost, "/pay", "key-1", `{"amount": 10}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, `{"calls":1}`, first.Body.String())
+
+	replay := do(http.MethodPost, "/pay", "key-1", `{"amount": 10}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, `{"calls":1}`, replay.Body.String())
+	assert.Equal(t, "application/json", replay.Header().Get("Content-Type"))
+	assert.Equal(t, "true", replay.Header().Get(serverutils.IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	// the same key can't be used for a different request
+	mismatch := do(http.MethodPost, "/pay", "key-1", `{"amount": 20}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatch.Code)
+	assert.Equal(t, 1, calls)
+
+	// requests without a key or that don't mutate are not stored
+	do(http.MethodPost, "/pay", "", `{"amount": 10}`)
+	do(http.MethodGet, "/pay", "key-2", ``)
+	do(http.MethodGet, "/pay", "key-2", ``)
+	assert.Equal(t, 4, calls)
+
+	// server errors can be retried
+	assert.Equal(t, http.StatusInternalServerError, do(http.MethodPost, "/fail", "key-3", ``).Code)
+	assert.Equal(t, http.StatusInternalServerError, do(http.MethodPost, "/fail", "key-3", ``).Code)
+	assert.Equal(t, 6, calls)
+}
+
+func TestIdempotencyMiddleware_ScopedToUser(t *testing.T) {
+	key, err := serverutils.NewHMACJWTKey([]byte("secret"))
+	assert.Nil(t, err)
+
+	calls := 0
+	handler := serverutils.JWTMiddleware(key, "api")(serverutils.IdempotencyMiddleware(serverutils.NewMemoryIdempotencyStore(), time.Minute)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			claims, _ := serverutils.JWTClaimsFromContext(r.Context())
+			serverutils.WriteJSONResponse(w, map[string]string{"uid": claims.UID}, http.StatusCreated)
+		}),
+	))
+
+	do := func(uid string) *httptest.ResponseRecorder {
+		token, err := key.IssueToken(serverutils.JWTClaims{Audience: "api", UID: uid}, time.Minute)
+		assert.Nil(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(`{"amount": 10}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(serverutils.IdempotencyKeyHeader, "key-1")
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+		return rw
+	}
+
+	assert.Equal(t, `{"uid":"alice"}`, do("alice").Body.String())
+	assert.Equal(t, `{"uid":"alice"}`, do("alice").Body.String())
+
+	// another user reusing the key gets their own response
+	assert.Equal(t, `{"uid":"mallory"}`, do("mallory").Body.String())
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_Panic(t *testing.T) {
+	calls := 0
+	handler := serverutils.IdempotencyMiddleware(serverutils.NewMemoryIdempotencyStore(), time.Minute)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(`{}`))
+		req.Header.Set(serverutils.IdempotencyKeyHeader, "key-1")
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+		return rw
+	}
+
+	func() {
+		defer func() {
+			assert.NotNil(t, recover())
+		}()
+		do()
+	}()
+
+	// the key is released so the retry isn't rejected as in progress
+	assert.Equal(t, http.StatusCreated, do().Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_LargeBody(t *testing.T) {
+	handler := serverutils.IdempotencyMiddleware(serverutils.NewMemoryIdempotencyStore(), time.Minute)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("the handler should not be called")
+		}),
+	)
+
+	body := strings.Repeat("a", serverutils.DefaultMaxBodyBytes+1)
+	req := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(body))
+	req.Header.Set(serverutils.IdempotencyKeyHeader, "key-1")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rw.Code)
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := serverutils.NewMemoryIdempotencyStore()
+
+	stored, err := store.Start(ctx, "key", "hash", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+
+	// the request is in progress
+	stored, err = store.Start(ctx, "key", "hash", time.Minute)
+	assert.Nil(t, err)
+	assert.NotNil(t, stored)
+	assert.False(t, stored.Completed())
+
+	err = store.Complete(ctx, "key", serverutils.IdempotentResponse{RequestHash: "hash", StatusCode: http.StatusOK, Body: []byte("ok")}, time.Minute)
+	assert.Nil(t, err)
+
+	stored, err = store.Start(ctx, "key", "hash", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, stored.Completed())
+	assert.Equal(t, []byte("ok"), stored.Body)
+
+	assert.Nil(t, store.Release(ctx, "key"))
+	stored, err = store.Start(ctx, "key", "hash", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+
+	// expired records are replaced
+	stored, err = store.Start(ctx, "expiring", "hash", time.Nanosecond)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+	time.Sleep(time.Millisecond)
+	stored, err = store.Start(ctx, "expiring", "hash", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+}
@@ -0,0 +1,360 @@
+package serverutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Inter-service client defaults
+const (
+	// InterserviceTokenTTL is how long inter-service JWTs are valid for
+	InterserviceTokenTTL = 5 * time.Minute
+
+	// InterserviceRequestTimeout is the maximum duration of a single inter-service request
+	InterserviceRequestTimeout = 30 * time.Second
+
+	// InterserviceMaxRetries is the number of times a failed inter-service request is retried
+	InterserviceMaxRetries = 3
+)
+
+// InterserviceTokenSource issues the tokens that authenticate a service to its sibling services.
+//
+// The audience is the base URL of the service being called.
+type InterserviceTokenSource interface {
+	Token(ctx context.Context, audience string) (string, error)
+}
+
+// InterserviceClaims are the claims carried by an inter-service JWT
+type InterserviceClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// HMACTokenSource issues short lived inter-service JWTs signed with a shared secret (HS256)
+type HMACTokenSource struct {
+	// Issuer is the name of the calling service
+	Issuer string
+	Secret []byte
+}
+
+// NewHMACTokenSourceFromEnv returns a token source that signs tokens with the secret in
+// the INTERSERVICE_JWT_SECRET environment variable
+func NewHMACTokenSourceFromEnv(issuer string) (*HMACTokenSource, error) {
+	secret, err := GetEnvVar(InterserviceJWTSecretEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	return &HMACTokenSource{Issuer: issuer, Secret: []byte(secret)}, nil
+}
+
+// Token implements the InterserviceTokenSource interface
+func (s *HMACTokenSource) Token(ctx context.Context, audience string) (string, error) {
+	now := time.Now()
+	claims := InterserviceClaims{
+		Issuer:    s.Issuer,
+		Audience:  audience,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(InterserviceTokenTTL).Unix(),
+	}
+	key, err := NewHMACJWTKey(s.Secret)
+	if err != nil {
+		return "", err
+	}
+	return key.SignJWT(claims)
+}
+
+// InterserviceTokenVerifier verifies the tokens presented by sibling services
+type InterserviceTokenVerifier interface {
+	Verify(ctx context.Context, token string) (*InterserviceClaims, error)
+}
+
+// HMACTokenVerifier verifies inter-service JWTs signed with a shared secret (HS256)
+type HMACTokenVerifier struct {
+	Secret []byte
+
+	// Audience is the base URL of the service verifying the tokens. It is required so that
+	// tokens meant for other services are rejected.
+	Audience string
+
+	// AllowedIssuers are the services that may call this service. Any issuer is allowed when empty.
+	AllowedIssuers []string
+}
+
+// NewHMACTokenVerifierFromEnv returns a token verifier that checks tokens against the secret
+// in the INTERSERVICE_JWT_SECRET environment variable
+func NewHMACTokenVerifierFromEnv(audience string, allowedIssuers ...string) (*HMACTokenVerifier, error) {
+	if audience == "" {
+		return nil, fmt.Errorf("the audience of the inter-service tokens is required")
+	}
+	secret, err := GetEnvVar(InterserviceJWTSecretEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	return &HMACTokenVerifier{Secret: []byte(secret), Audience: audience, AllowedIssuers: allowedIssuers}, nil
+}
+
+// Verify implements the InterserviceTokenVerifier interface
+func (v *HMACTokenVerifier) Verify(ctx context.Context, token string) (*InterserviceClaims, error) {
+	if v.Audience == "" {
+		return nil, fmt.Errorf("the audience of the inter-service tokens is required")
+	}
+	key, err := NewHMACJWTKey(v.Secret)
+	if err != nil {
+		return nil, err
+	}
+	claims := &InterserviceClaims{}
+	err = key.ParseJWT(token, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("the inter-service token has expired")
+	}
+	if strings.TrimRight(claims.Audience, "/") != strings.TrimRight(v.Audience, "/") {
+		return nil, fmt.Errorf("the inter-service token is meant for %s", claims.Audience)
+	}
+	if len(v.AllowedIssuers) > 0 && !containsString(v.AllowedIssuers, claims.Issuer) {
+		return nil, fmt.Errorf("the %s service is not allowed to call this service", claims.Issuer)
+	}
+	return claims, nil
+}
+
+// BearerToken returns the token in the `Authorization: Bearer <token>` header of a request
+func BearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", fmt.Errorf("the Authorization header is not set")
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", fmt.Errorf("the Authorization header is not a bearer token")
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// VerifyInterserviceJWT protects internal endpoints so that only sibling services
+// presenting a valid inter-service token can call them.
+//
+// Requests without a valid token are rejected with a 401 status. The claims of valid
+// tokens are available to the next handler via InterserviceClaimsFromContext.
+func VerifyInterserviceJWT(verifier InterserviceTokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				token, err := BearerToken(r)
+				if err != nil {
+					WriteJSONResponse(w, ErrorMap(err), http.StatusUnauthorized)
+					return
+				}
+
+				claims, err := verifier.Verify(r.Context(), token)
+				if err != nil {
+					WriteJSONResponse(w, ErrorMap(err), http.StatusUnauthorized)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), interserviceClaimsContextKey, claims)
+				next.ServeHTTP(w, r.WithContext(ctx))
+			},
+		)
+	}
+}
+
+// InterserviceClaimsFromContext returns the claims of the inter-service token verified by
+// VerifyInterserviceJWT
+func InterserviceClaimsFromContext(ctx context.Context) (*InterserviceClaims, bool) {
+	claims, ok := ctx.Value(interserviceClaimsContextKey).(*InterserviceClaims)
+	return claims, ok
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// InterserviceError is returned when a sibling service responds with an unsuccessful status
+type InterserviceError struct {
+	Service    string
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *InterserviceError) Error() string {
+	return fmt.Sprintf("%s service responded with status %d: %s", e.Service, e.StatusCode, e.Body)
+}
+
+// InterserviceClient calls sibling services e.g other Cloud Run services of the same product.
+//
+// Requests are authenticated with a token from the client's token source, traced, measured
+// and retried when the called service is temporarily unavailable.
+type InterserviceClient struct {
+	httpClient  *http.Client
+	tokenSource InterserviceTokenSource
+	services    map[string]string
+}
+
+// NewInterserviceClient returns an initialized inter-service client.
+//
+// services maps service names to their base URLs. Services that are not in the map are
+// resolved from the environment (see ResolveServiceURL).
+func NewInterserviceClient(tokenSource InterserviceTokenSource, services map[string]string) *InterserviceClient {
+	if services == nil {
+		services = map[string]string{}
+	}
+	return &InterserviceClient{
+		httpClient: &http.Client{
+			Timeout:   InterserviceRequestTimeout,
+			Transport: NewTracingTransport(NewMetricsTransport(NewCircuitBreakerTransport(nil, 0, 0))),
+		},
+		tokenSource: tokenSource,
+		services:    services,
+	}
+}
+
+// ResolveServiceURL returns the base URL of the named service.
+//
+// It is looked up in the client's services and then in the `<NAME>_SERVICE_URL`
+// environment variable e.g the URL of the `onboarding` service is read from ONBOARDING_SERVICE_URL.
+func (c *InterserviceClient) ResolveServiceURL(service string) (string, error) {
+	if url, ok := c.services[service]; ok && url != "" {
+		return strings.TrimRight(url, "/"), nil
+	}
+
+	envVarName := strings.ToUpper(strings.ReplaceAll(service, "-", "_")) + ServiceURLEnvVarSuffix
+	url, err := GetEnvVar(envVarName)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve the URL of the %s service: %w", service, err)
+	}
+	return strings.TrimRight(url, "/"), nil
+}
+
+// MakeInterserviceRequest sends the supplied payload as JSON to the path of the named service
+// and decodes the JSON response into out.
+//
+// payload and out may be nil. GET, HEAD, OPTIONS, PUT and DELETE requests that fail to connect
+// or get a 502, 503 or 504 response are retried with an exponential backoff, so the endpoints
+// called with these methods should be idempotent. POST and PATCH requests are never retried
+// because the called service may have processed them before failing.
+// Services that keep failing are not called until their circuit breaker lets a probe through,
+// in the meantime requests fail fast with ErrCircuitOpen.
+func (c *InterserviceClient) MakeInterserviceRequest(
+	ctx context.Context,
+	method, service, path string,
+	payload, out interface{},
+) error {
+	baseURL, err := c.ResolveServiceURL(service)
+	if err != nil {
+		return err
+	}
+	url := baseURL + "/" + strings.TrimLeft(path, "/")
+
+	var body []byte
+	if payload != nil {
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("unable to marshal the payload for the %s service: %w", service, err)
+		}
+	}
+
+	token, err := c.tokenSource.Token(ctx, baseURL)
+	if err != nil {
+		return fmt.Errorf("unable to get an inter-service token for the %s service: %w", service, err)
+	}
+
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("unable to compose the request to the %s service: %w", service, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/json")
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err = c.httpClient.Do(req)
+		if !isIdempotentMethod(method) || !shouldRetry(resp, err) || attempt >= InterserviceMaxRetries {
+			if err != nil {
+				return fmt.Errorf("unable to call the %s service: %w", service, err)
+			}
+			break
+		}
+		if resp != nil {
+			closeBody(resp)
+		}
+
+		backoff := time.Duration(1<<attempt) * 100 * time.Millisecond
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	defer closeBody(resp)
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read the response of the %s service: %w", service, err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &InterserviceError{Service: service, StatusCode: resp.StatusCode, Body: string(content)}
+	}
+
+	if out == nil || len(content) == 0 {
+		return nil
+	}
+	err = json.Unmarshal(content, out)
+	if err != nil {
+		return fmt.Errorf("unable to decode the response of the %s service: %w", service, err)
+	}
+	return nil
+}
+
+// isIdempotentMethod reports whether a request with the method can safely be sent again
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether a request failed in a way that may succeed if retried
+func shouldRetry(resp *http.Response, err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func closeBody(resp *http.Response) {
+	err := resp.Body.Close()
+	if err != nil {
+		Logger().Println(err)
+	}
+}
@@ -0,0 +1,30 @@
+package serverutils_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoogleIDTokenVerifier_Verify(t *testing.T) {
+	verifier := &serverutils.GoogleIDTokenVerifier{Audience: "https://engagement.example.com"}
+	_, err := verifier.Verify(context.Background(), "not-a-google-id-token")
+	assert.NotNil(t, err)
+
+	// tokens issued for any audience must not be accepted
+	_, err = (&serverutils.GoogleIDTokenVerifier{}).Verify(context.Background(), "not-a-google-id-token")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "audience")
+}
+
+func TestGoogleIDTokenSource_Token(t *testing.T) {
+	source := serverutils.NewGoogleIDTokenSource()
+	token, err := source.Token(context.Background(), "https://engagement.example.com")
+	if err != nil {
+		// outside GCP, or without service account credentials, no ID token can be issued
+		t.Skipf("unable to get a Google ID token: %v", err)
+	}
+	assert.NotEmpty(t, token)
+}
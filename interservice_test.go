@@ -0,0 +1,331 @@
+package serverutils_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeJWTClaims(t *testing.T, token string) serverutils.InterserviceClaims {
+	parts := strings.Split(token, ".")
+	assert.Len(t, parts, 3)
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.Nil(t, err)
+
+	claims := serverutils.InterserviceClaims{}
+	assert.Nil(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+func TestHMACTokenSource_Token(t *testing.T) {
+	source := &serverutils.HMACTokenSource{Issuer: "onboarding", Secret: []byte("secret")}
+	token, err := source.Token(context.Background(), "https://engagement.example.com")
+	assert.Nil(t, err)
+
+	claims := decodeJWTClaims(t, token)
+	assert.Equal(t, "onboarding", claims.Issuer)
+	assert.Equal(t, "https://engagement.example.com", claims.Audience)
+	assert.True(t, claims.ExpiresAt > claims.IssuedAt)
+
+	_, err = (&serverutils.HMACTokenSource{Issuer: "onboarding"}).Token(context.Background(), "aud")
+	assert.NotNil(t, err)
+}
+
+func TestNewHMACTokenSourceFromEnv(t *testing.T) {
+	setEnv(t, map[string]string{serverutils.InterserviceJWTSecretEnvVarName: ""})
+	_, err := serverutils.NewHMACTokenSourceFromEnv("onboarding")
+	assert.NotNil(t, err)
+
+	setEnv(t, map[string]string{serverutils.InterserviceJWTSecretEnvVarName: "secret"})
+	source, err := serverutils.NewHMACTokenSourceFromEnv("onboarding")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), source.Secret)
+}
+
+func TestInterserviceClient_ResolveServiceURL(t *testing.T) {
+	setEnv(t, map[string]string{"PAYMENT_GATEWAY_SERVICE_URL": "https://payments.example.com/"})
+	client := serverutils.NewInterserviceClient(nil, map[string]string{
+		"onboarding": "https://onboarding.example.com",
+	})
+
+	url, err := client.ResolveServiceURL("onboarding")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://onboarding.example.com", url)
+
+	url, err = client.ResolveServiceURL("payment-gateway")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://payments.example.com", url)
+
+	_, err = client.ResolveServiceURL("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestInterserviceClient_MakeInterserviceRequest(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch r.URL.Path {
+		case "/flaky":
+			if attempts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"not found"}`))
+			return
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		claims := decodeJWTClaims(t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		p := payload{}
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		_ = json.NewEncoder(w).Encode(map[string]string{"name": p.Name, "issuer": claims.Issuer})
+	}))
+	defer srv.Close()
+
+	source := &serverutils.HMACTokenSource{Issuer: "onboarding", Secret: []byte("secret")}
+	client := serverutils.NewInterserviceClient(source, map[string]string{"engagement": srv.URL})
+	ctx := context.Background()
+
+	out := map[string]string{}
+	err := client.MakeInterserviceRequest(ctx, http.MethodPost, "engagement", "/feed", payload{Name: "test"}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", out["name"])
+	assert.Equal(t, "onboarding", out["issuer"])
+
+	attempts = 0
+	out = map[string]string{}
+	err = client.MakeInterserviceRequest(ctx, http.MethodGet, "engagement", "flaky", nil, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// requests that may not be idempotent are not retried
+	attempts = 0
+	err = client.MakeInterserviceRequest(ctx, http.MethodPost, "engagement", "flaky", payload{Name: "test"}, &out)
+	var flakyErr *serverutils.InterserviceError
+	assert.True(t, errors.As(err, &flakyErr))
+	assert.Equal(t, http.StatusServiceUnavailable, flakyErr.StatusCode)
+	assert.Equal(t, 1, attempts)
+
+	err = client.MakeInterserviceRequest(ctx, http.MethodDelete, "engagement", "/empty", nil, &out)
+	assert.Nil(t, err)
+
+	err = client.MakeInterserviceRequest(ctx, http.MethodGet, "engagement", "/missing", nil, &out)
+	var interserviceErr *serverutils.InterserviceError
+	assert.True(t, errors.As(err, &interserviceErr))
+	assert.Equal(t, http.StatusNotFound, interserviceErr.StatusCode)
+
+	err = client.MakeInterserviceRequest(ctx, http.MethodGet, "unknown", "/", nil, nil)
+	assert.NotNil(t, err)
+
+	err = client.MakeInterserviceRequest(ctx, http.MethodPost, "engagement", "/", make(chan int), nil)
+	assert.NotNil(t, err)
+}
+
+func TestInterserviceClient_Unreachable(t *testing.T) {
+	source := &serverutils.HMACTokenSource{Issuer: "onboarding", Secret: []byte("secret")}
+	client := serverutils.NewInterserviceClient(source, map[string]string{"engagement": "http://127.0.0.1:0"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := client.MakeInterserviceRequest(ctx, http.MethodGet, "engagement", "/", nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestHMACTokenVerifier_Verify(t *testing.T) {
+	ctx := context.Background()
+	audience := "https://engagement.example.com"
+	source := &serverutils.HMACTokenSource{Issuer: "onboarding", Secret: []byte("secret")}
+	token, err := source.Token(ctx, audience)
+	assert.Nil(t, err)
+
+	wrongAudience, err := source.Token(ctx, "https://other.example.com")
+	assert.Nil(t, err)
+
+	noAudience, err := source.Token(ctx, "")
+	assert.Nil(t, err)
+
+	key, err := serverutils.NewHMACJWTKey([]byte("secret"))
+	assert.Nil(t, err)
+	expired, err := key.SignJWT(serverutils.InterserviceClaims{
+		Issuer:    "onboarding",
+		Audience:  audience,
+		IssuedAt:  time.Now().Add(-time.Hour).Unix(),
+		ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		verifier *serverutils.HMACTokenVerifier
+		token    string
+		wantErr  bool
+	}{
+		{
+			name:     "valid token",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("secret"), Audience: audience + "/"},
+			token:    token,
+			wantErr:  false,
+		},
+		{
+			name:     "allowed issuer",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("secret"), Audience: audience, AllowedIssuers: []string{"onboarding"}},
+			token:    token,
+			wantErr:  false,
+		},
+		{
+			name:     "issuer not allowed",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("secret"), Audience: audience, AllowedIssuers: []string{"payments"}},
+			token:    token,
+			wantErr:  true,
+		},
+		{
+			name:     "wrong secret",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("another secret"), Audience: audience},
+			token:    token,
+			wantErr:  true,
+		},
+		{
+			name:     "wrong audience",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("secret"), Audience: audience},
+			token:    wrongAudience,
+			wantErr:  true,
+		},
+		{
+			name:     "expired token",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("secret"), Audience: audience},
+			token:    expired,
+			wantErr:  true,
+		},
+		{
+			name:     "no audience",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("secret")},
+			token:    noAudience,
+			wantErr:  true,
+		},
+		{
+			name:     "unsigned token",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("secret"), Audience: audience},
+			token:    "eyJhbGciOiJub25lIn0.eyJpc3MiOiJvbmJvYXJkaW5nIn0.",
+			wantErr:  true,
+		},
+		{
+			name:     "malformed token",
+			verifier: &serverutils.HMACTokenVerifier{Secret: []byte("secret"), Audience: audience},
+			token:    "not-a-jwt",
+			wantErr:  true,
+		},
+		{
+			name:     "no secret",
+			verifier: &serverutils.HMACTokenVerifier{Audience: audience},
+			token:    token,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.verifier.Verify(ctx, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HMACTokenVerifier.Verify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				assert.Equal(t, "onboarding", claims.Issuer)
+			}
+		})
+	}
+}
+
+func TestNewHMACTokenVerifierFromEnv(t *testing.T) {
+	setEnv(t, map[string]string{serverutils.InterserviceJWTSecretEnvVarName: ""})
+	_, err := serverutils.NewHMACTokenVerifierFromEnv("https://engagement.example.com")
+	assert.NotNil(t, err)
+
+	setEnv(t, map[string]string{serverutils.InterserviceJWTSecretEnvVarName: "secret"})
+	_, err = serverutils.NewHMACTokenVerifierFromEnv("")
+	assert.NotNil(t, err)
+
+	verifier, err := serverutils.NewHMACTokenVerifierFromEnv("https://engagement.example.com", "onboarding")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"onboarding"}, verifier.AllowedIssuers)
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{name: "bearer token", header: "Bearer abc.def.ghi", want: "abc.def.ghi"},
+		{name: "case insensitive scheme", header: "bearer abc", want: "abc"},
+		{name: "missing header", header: "", wantErr: true},
+		{name: "basic auth", header: "Basic dXNlcjpwYXNz", wantErr: true},
+		{name: "empty token", header: "Bearer ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			got, err := serverutils.BearerToken(req)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVerifyInterserviceJWT(t *testing.T) {
+	audience := "https://engagement.example.com"
+	source := &serverutils.HMACTokenSource{Issuer: "onboarding", Secret: []byte("secret")}
+	verifier := &serverutils.HMACTokenVerifier{Secret: []byte("secret"), Audience: audience}
+
+	var issuer string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := serverutils.InterserviceClaimsFromContext(r.Context())
+		assert.True(t, ok)
+		issuer = claims.Issuer
+	})
+	h := serverutils.VerifyInterserviceJWT(verifier)(next)
+
+	token, err := source.Token(context.Background(), audience)
+	assert.Nil(t, err)
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/internal", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	h.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "onboarding", issuer)
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/internal", nil))
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+
+	rw = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/internal", nil)
+	req.Header.Set("Authorization", "Bearer forged.token.value")
+	h.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+
+	_, ok := serverutils.InterserviceClaimsFromContext(context.Background())
+	assert.False(t, ok)
+}
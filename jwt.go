@@ -0,0 +1,368 @@
+package serverutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Supported JWT signing algorithms
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+)
+
+// JWTKey signs and verifies JWTs with either a shared secret (HS256) or an RSA key pair (RS256).
+//
+// An RSA key created from a public key alone can only verify tokens.
+type JWTKey struct {
+	algorithm string
+	secret    []byte
+	private   *rsa.PrivateKey
+	public    *rsa.PublicKey
+}
+
+// NewHMACJWTKey returns a key that signs and verifies JWTs with HMAC SHA-256
+func NewHMACJWTKey(secret []byte) (*JWTKey, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("a secret is required to sign and verify JWTs")
+	}
+	return &JWTKey{algorithm: JWTAlgorithmHS256, secret: secret}, nil
+}
+
+// NewRSAJWTKey returns a key that signs and verifies JWTs with the supplied RSA private key
+func NewRSAJWTKey(private *rsa.PrivateKey) (*JWTKey, error) {
+	if private == nil {
+		return nil, fmt.Errorf("a RSA private key is required to sign and verify JWTs")
+	}
+	return &JWTKey{algorithm: JWTAlgorithmRS256, private: private, public: &private.PublicKey}, nil
+}
+
+// NewRSAJWTVerificationKey returns a key that verifies JWTs with the supplied RSA public key
+func NewRSAJWTVerificationKey(public *rsa.PublicKey) (*JWTKey, error) {
+	if public == nil {
+		return nil, fmt.Errorf("a RSA public key is required to verify JWTs")
+	}
+	return &JWTKey{algorithm: JWTAlgorithmRS256, public: public}, nil
+}
+
+// ParseRSAJWTKeyPEM returns a RSA JWT key from a PEM encoded private key (PKCS #1 or PKCS #8)
+// or public key (PKIX or PKCS #1)
+func ParseRSAJWTKeyPEM(data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA private key: %w", err)
+		}
+		return NewRSAJWTKey(private)
+
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		private, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("the private key is not a RSA key")
+		}
+		return NewRSAJWTKey(private)
+
+	case "RSA PUBLIC KEY":
+		public, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA public key: %w", err)
+		}
+		return NewRSAJWTVerificationKey(public)
+
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		public, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("the public key is not a RSA key")
+		}
+		return NewRSAJWTVerificationKey(public)
+	}
+	return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+}
+
+// LoadJWTKeyFromEnv returns the JWT key configured in the environment.
+//
+// JWT_PRIVATE_KEY, JWT_PUBLIC_KEY and JWT_SECRET are tried in that order.
+// Secrets stored in Secret Manager can be exposed to Cloud Run services as environment variables.
+func LoadJWTKeyFromEnv() (*JWTKey, error) {
+	if private, err := GetEnvVar(JWTPrivateKeyEnvVarName); err == nil && private != "" {
+		return ParseRSAJWTKeyPEM([]byte(private))
+	}
+	if public, err := GetEnvVar(JWTPublicKeyEnvVarName); err == nil && public != "" {
+		return ParseRSAJWTKeyPEM([]byte(public))
+	}
+	secret, err := GetEnvVar(JWTSecretEnvVarName)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"none of %s, %s or %s is set: %w",
+			JWTPrivateKeyEnvVarName, JWTPublicKeyEnvVarName, JWTSecretEnvVarName, err,
+		)
+	}
+	return NewHMACJWTKey([]byte(secret))
+}
+
+// Algorithm returns the signing algorithm of the key e.g HS256
+func (k *JWTKey) Algorithm() string {
+	return k.algorithm
+}
+
+// jwtHeader is the JOSE header of a JWT. Other header parameters e.g `kid` are ignored.
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// SignJWT encodes the supplied claims as a signed JWT
+func (k *JWTKey) SignJWT(claims interface{}) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: k.algorithm, Type: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal the JWT header: %w", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal the JWT claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := k.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ParseJWT checks the signature of a JWT and decodes its claims into the supplied value.
+//
+// It does not validate the claims e.g the expiry; see VerifyToken.
+func (k *JWTKey) ParseJWT(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed JWT")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("malformed JWT header: %w", err)
+	}
+	header := jwtHeader{}
+	err = json.Unmarshal(headerJSON, &header)
+	if err != nil {
+		return fmt.Errorf("malformed JWT header: %w", err)
+	}
+	// only accept the key's algorithm e.g never `none`, or HS256 signed with a RSA public key
+	if header.Algorithm != k.algorithm {
+		return fmt.Errorf("unexpected JWT signing algorithm %q", header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("malformed JWT signature: %w", err)
+	}
+	err = k.verify([]byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("malformed JWT claims: %w", err)
+	}
+	err = json.Unmarshal(payload, claims)
+	if err != nil {
+		return fmt.Errorf("malformed JWT claims: %w", err)
+	}
+	return nil
+}
+
+func (k *JWTKey) sign(input []byte) ([]byte, error) {
+	switch k.algorithm {
+	case JWTAlgorithmHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		_, err := mac.Write(input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign the JWT: %w", err)
+		}
+		return mac.Sum(nil), nil
+
+	case JWTAlgorithmRS256:
+		if k.private == nil {
+			return nil, fmt.Errorf("a private key is required to sign a JWT")
+		}
+		digest := sha256.Sum256(input)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, k.private, crypto.SHA256, digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign the JWT: %w", err)
+		}
+		return signature, nil
+	}
+	return nil, fmt.Errorf("unsupported JWT signing algorithm %q", k.algorithm)
+}
+
+func (k *JWTKey) verify(input []byte, signature []byte) error {
+	switch k.algorithm {
+	case JWTAlgorithmHS256:
+		expected, err := k.sign(input)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal(signature, expected) {
+			return fmt.Errorf("invalid JWT signature")
+		}
+		return nil
+
+	case JWTAlgorithmRS256:
+		digest := sha256.Sum256(input)
+		err := rsa.VerifyPKCS1v15(k.public, crypto.SHA256, digest[:], signature)
+		if err != nil {
+			return fmt.Errorf("invalid JWT signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported JWT signing algorithm %q", k.algorithm)
+}
+
+// JWTClaims are the claims of the tokens issued with IssueToken
+type JWTClaims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti,omitempty"`
+
+	// UID is the ID of the user the token was issued to
+	UID string `json:"sub"`
+
+	Permissions []string `json:"permissions,omitempty"`
+
+	// Flavour, OrganizationID and LocationID are added to the request context by JWTMiddleware
+	Flavour        string `json:"flavour,omitempty"`
+	OrganizationID string `json:"organizationID,omitempty"`
+	LocationID     string `json:"locationID,omitempty"`
+}
+
+// HasPermission reports whether the token grants the supplied permission
+func (c *JWTClaims) HasPermission(permission string) bool {
+	return containsString(c.Permissions, permission)
+}
+
+// IssueToken signs a token with the supplied claims that expires after the supplied duration.
+// The issued at, expiry and ID claims are set by IssueToken.
+func (k *JWTKey) IssueToken(claims JWTClaims, ttl time.Duration) (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate a JWT ID: %w", err)
+	}
+
+	now := time.Now()
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+	claims.ID = hex.EncodeToString(id)
+	return k.SignJWT(claims)
+}
+
+// VerifyToken checks the signature, expiry and audience of a token issued with IssueToken
+// and returns its claims
+func (k *JWTKey) VerifyToken(token string, audience string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+	err := k.ParseJWT(token, claims)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("the JWT has expired")
+	}
+	if claims.Audience != audience {
+		return nil, fmt.Errorf("the JWT is meant for %q", claims.Audience)
+	}
+	return claims, nil
+}
+
+// JWTMiddleware rejects requests that do not carry a valid bearer token issued for the
+// supplied audience with a 401 status.
+//
+// The claims of valid tokens are available to the next handler via JWTClaimsFromContext.
+func JWTMiddleware(key *JWTKey, audience string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				token, err := BearerToken(r)
+				if err != nil {
+					WriteJSONResponse(w, ErrorMap(err), http.StatusUnauthorized)
+					return
+				}
+
+				claims, err := key.VerifyToken(token, audience)
+				if err != nil {
+					WriteJSONResponse(w, ErrorMap(err), http.StatusUnauthorized)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), jwtClaimsContextKey, claims)
+				if claims.Flavour != "" {
+					ctx = WithFlavour(ctx, claims.Flavour)
+				}
+				if claims.OrganizationID != "" {
+					ctx = WithOrganizationID(ctx, claims.OrganizationID)
+				}
+				if claims.LocationID != "" {
+					ctx = WithLocationID(ctx, claims.LocationID)
+				}
+				next.ServeHTTP(w, r.WithContext(ctx))
+			},
+		)
+	}
+}
+
+// JWTClaimsFromContext returns the claims of the token verified by JWTMiddleware
+func JWTClaimsFromContext(ctx context.Context) (*JWTClaims, bool) {
+	claims, ok := ctx.Value(jwtClaimsContextKey).(*JWTClaims)
+	return claims, ok
+}
+
+// RequirePermission rejects requests whose token, verified by JWTMiddleware, does not grant
+// the supplied permission with a 403 status
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				claims, ok := JWTClaimsFromContext(r.Context())
+				if !ok {
+					WriteJSONResponse(w, ErrorMap(fmt.Errorf("the request is not authenticated")), http.StatusUnauthorized)
+					return
+				}
+				if !claims.HasPermission(permission) {
+					WriteJSONResponse(w, ErrorMap(fmt.Errorf("the %s permission is required", permission)), http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
@@ -0,0 +1,225 @@
+package serverutils_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func rsaKeyPEM(t *testing.T) (private []byte, public []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	private = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	public = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	return private, public
+}
+
+func TestJWTKey_IssueToken(t *testing.T) {
+	privatePEM, publicPEM := rsaKeyPEM(t)
+	rsaKey, err := serverutils.ParseRSAJWTKeyPEM(privatePEM)
+	assert.Nil(t, err)
+	rsaVerificationKey, err := serverutils.ParseRSAJWTKeyPEM(publicPEM)
+	assert.Nil(t, err)
+	hmacKey, err := serverutils.NewHMACJWTKey([]byte("secret"))
+	assert.Nil(t, err)
+	otherHMACKey, err := serverutils.NewHMACJWTKey([]byte("another secret"))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name        string
+		signingKey  *serverutils.JWTKey
+		verifyKey   *serverutils.JWTKey
+		ttl         time.Duration
+		audience    string
+		wantErr     bool
+		wantSignErr bool
+	}{
+		{name: "HS256", signingKey: hmacKey, verifyKey: hmacKey, ttl: time.Minute, audience: "api"},
+		{name: "RS256", signingKey: rsaKey, verifyKey: rsaVerificationKey, ttl: time.Minute, audience: "api"},
+		{name: "wrong secret", signingKey: hmacKey, verifyKey: otherHMACKey, ttl: time.Minute, audience: "api", wantErr: true},
+		{name: "algorithm mismatch", signingKey: hmacKey, verifyKey: rsaVerificationKey, ttl: time.Minute, audience: "api", wantErr: true},
+		{name: "expired", signingKey: hmacKey, verifyKey: hmacKey, ttl: -time.Minute, audience: "api", wantErr: true},
+		{name: "wrong audience", signingKey: hmacKey, verifyKey: hmacKey, ttl: time.Minute, audience: "other", wantErr: true},
+		{name: "public key can't sign", signingKey: rsaVerificationKey, wantSignErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.signingKey.IssueToken(serverutils.JWTClaims{
+				Audience:    "api",
+				UID:         "user-1",
+				Permissions: []string{"read"},
+			}, tt.ttl)
+			if tt.wantSignErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+
+			claims, err := tt.verifyKey.VerifyToken(token, tt.audience)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "user-1", claims.UID)
+			assert.NotEmpty(t, claims.ID)
+			assert.True(t, claims.HasPermission("read"))
+			assert.False(t, claims.HasPermission("write"))
+		})
+	}
+}
+
+func TestParseRSAJWTKeyPEM(t *testing.T) {
+	_, err := serverutils.ParseRSAJWTKeyPEM([]byte("not PEM"))
+	assert.NotNil(t, err)
+
+	_, err = serverutils.ParseRSAJWTKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")}))
+	assert.NotNil(t, err)
+
+	_, err = serverutils.NewHMACJWTKey(nil)
+	assert.NotNil(t, err)
+
+	_, err = serverutils.NewRSAJWTKey(nil)
+	assert.NotNil(t, err)
+
+	_, err = serverutils.NewRSAJWTVerificationKey(nil)
+	assert.NotNil(t, err)
+}
+
+func TestJWTKey_ParseJWT_Header(t *testing.T) {
+	key, err := serverutils.NewHMACJWTKey([]byte("secret"))
+	assert.Nil(t, err)
+	token, err := key.SignJWT(map[string]string{"sub": "user-1"})
+	assert.Nil(t, err)
+	parts := strings.Split(token, ".")
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{name: "signed header", header: parts[0]},
+		{name: "none algorithm", header: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)), wantErr: true},
+		{name: "not an object", header: base64.RawURLEncoding.EncodeToString([]byte(`["HS256"]`)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]string{}
+			err := key.ParseJWT(tt.header+"."+parts[1]+"."+parts[2], &claims)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "user-1", claims["sub"])
+		})
+	}
+}
+
+func TestLoadJWTKeyFromEnv(t *testing.T) {
+	_, publicPEM := rsaKeyPEM(t)
+
+	setEnv(t, map[string]string{
+		serverutils.JWTPrivateKeyEnvVarName: "",
+		serverutils.JWTPublicKeyEnvVarName:  "",
+		serverutils.JWTSecretEnvVarName:     "",
+	})
+	_, err := serverutils.LoadJWTKeyFromEnv()
+	assert.NotNil(t, err)
+
+	setEnv(t, map[string]string{serverutils.JWTSecretEnvVarName: "secret"})
+	key, err := serverutils.LoadJWTKeyFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, serverutils.JWTAlgorithmHS256, key.Algorithm())
+
+	setEnv(t, map[string]string{serverutils.JWTPublicKeyEnvVarName: string(publicPEM)})
+	key, err = serverutils.LoadJWTKeyFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, serverutils.JWTAlgorithmRS256, key.Algorithm())
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	key, err := serverutils.NewHMACJWTKey([]byte("secret"))
+	assert.Nil(t, err)
+
+	reader, err := key.IssueToken(serverutils.JWTClaims{Audience: "api", UID: "reader", Permissions: []string{"read"}}, time.Minute)
+	assert.Nil(t, err)
+	writer, err := key.IssueToken(serverutils.JWTClaims{Audience: "api", UID: "writer", Permissions: []string{"read", "write"}}, time.Minute)
+	assert.Nil(t, err)
+
+	handler := serverutils.JWTMiddleware(key, "api")(serverutils.RequirePermission("write")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := serverutils.JWTClaimsFromContext(r.Context())
+			assert.True(t, ok)
+			assert.Equal(t, "writer", claims.UID)
+			w.WriteHeader(http.StatusOK)
+		}),
+	))
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "permitted", token: writer, wantStatus: http.StatusOK},
+		{name: "missing permission", token: reader, wantStatus: http.StatusForbidden},
+		{name: "invalid token", token: "not-a-token", wantStatus: http.StatusUnauthorized},
+		{name: "no token", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+			assert.Equal(t, tt.wantStatus, rw.Code)
+		})
+	}
+
+	// RequirePermission needs an authenticated request
+	rw := httptest.NewRecorder()
+	serverutils.RequirePermission("read")(http.NotFoundHandler()).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+}
+
+func TestJWTMiddleware_ContextMetadata(t *testing.T) {
+	key, err := serverutils.NewHMACJWTKey([]byte("secret"))
+	assert.Nil(t, err)
+
+	token, err := key.IssueToken(serverutils.JWTClaims{
+		Audience:       "api",
+		UID:            "user",
+		Flavour:        "PRO",
+		OrganizationID: "org-1",
+		LocationID:     "loc-1",
+	}, time.Minute)
+	assert.Nil(t, err)
+
+	handler := serverutils.JWTMiddleware(key, "api")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flavour, _ := serverutils.FlavourFromContext(r.Context())
+		organizationID, _ := serverutils.OrganizationIDFromContext(r.Context())
+		locationID, _ := serverutils.LocationIDFromContext(r.Context())
+		assert.Equal(t, "PRO", flavour)
+		assert.Equal(t, "org-1", organizationID)
+		assert.Equal(t, "loc-1", locationID)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
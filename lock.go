@@ -0,0 +1,145 @@
+package serverutils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLockNotAcquired is returned when a lock is held by someone else
+var ErrLockNotAcquired = errors.New("the lock is held by someone else")
+
+// lock acquisition polling intervals
+const (
+	lockRetryInterval    = 25 * time.Millisecond
+	maxLockRetryInterval = 200 * time.Millisecond
+)
+
+// Locker provides mutual exclusion on keys, e.g across the instances of a service.
+//
+// Acquire takes the lock on key for ttl and returns a token that identifies the holder,
+// or ErrLockNotAcquired when the lock is held. The lock is released automatically once
+// the ttl elapses so a crashed holder can't block others forever.
+// Release only releases a lock that is still held with the supplied token.
+type Locker interface {
+	Acquire(ctx context.Context, key string, ttl time.Duration) (string, error)
+	Release(ctx context.Context, key string, token string) error
+}
+
+// Lock waits until the lock on key is acquired, or ctx is done, and returns a function
+// that releases it
+func Lock(ctx context.Context, locker Locker, key string, ttl time.Duration) (func() error, error) {
+	interval := lockRetryInterval
+	for {
+		token, err := locker.Acquire(ctx, key, ttl)
+		if err == nil {
+			return func() error {
+				// release even if ctx has since been cancelled
+				return locker.Release(context.Background(), key, token)
+			}, nil
+		}
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return nil, fmt.Errorf("unable to acquire the %s lock: %w", key, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("unable to acquire the %s lock: %w", key, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > maxLockRetryInterval {
+			interval = maxLockRetryInterval
+		}
+	}
+}
+
+// WithLock runs fn while holding the lock on key.
+//
+// fn should finish well within the ttl; the lock is not extended while fn runs.
+func WithLock(ctx context.Context, locker Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	release, err := Lock(ctx, locker, key, ttl)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := release()
+		if err != nil {
+			Logger().Println(err)
+		}
+	}()
+	return fn(ctx)
+}
+
+type heldLock struct {
+	token   string
+	expires time.Time
+}
+
+// MemoryLocker is an in-process Locker.
+//
+// It only provides mutual exclusion within a single instance of a service; use a
+// CloudStorageLocker to coordinate several instances.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]heldLock
+	calls int
+}
+
+// NewMemoryLocker returns an initialized in-process locker
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]heldLock)}
+}
+
+// number of calls to Acquire between sweeps of expired locks
+const lockSweepInterval = 1000
+
+// Acquire implements the Locker interface
+func (l *MemoryLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	l.calls++
+	if l.calls%lockSweepInterval == 0 {
+		l.sweep(now)
+	}
+
+	if held, ok := l.locks[key]; ok && now.Before(held.expires) {
+		return "", ErrLockNotAcquired
+	}
+
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate a lock token: %w", err)
+	}
+	l.locks[key] = heldLock{token: hex.EncodeToString(token), expires: now.Add(ttl)}
+	return l.locks[key].token, nil
+}
+
+// Release implements the Locker interface
+func (l *MemoryLocker) Release(ctx context.Context, key string, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	held, ok := l.locks[key]
+	if !ok || held.token != token {
+		return fmt.Errorf("the %s lock is not held with the supplied token", key)
+	}
+	delete(l.locks, key)
+	return nil
+}
+
+func (l *MemoryLocker) sweep(now time.Time) {
+	for key, held := range l.locks {
+		if !now.Before(held.expires) {
+			delete(l.locks, key)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package serverutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// the object metadata that records when a lock expires
+const lockExpiresMetadataKey = "lock-expires"
+
+// CloudStorageLocker is a Locker backed by a Google Cloud Storage bucket, so that it
+// provides mutual exclusion across the instances of a service.
+//
+// Each held lock is an object named `locks/<key>` that is created only if it doesn't
+// exist yet. The token is the object's generation so a lock that expired and was taken
+// over can't be released by its previous holder. Expiry is checked against the clock of
+// the instance taking over the lock, so the ttl should be well above the clock skew
+// between instances.
+type CloudStorageLocker struct {
+	service *storage.Service
+	bucket  string
+}
+
+// NewCloudStorageLocker returns a locker that keeps its locks in the supplied bucket
+func NewCloudStorageLocker(ctx context.Context, bucket string) (*CloudStorageLocker, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("the bucket is required")
+	}
+	service, err := storage.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize the Cloud Storage client: %w", err)
+	}
+	return &CloudStorageLocker{service: service, bucket: bucket}, nil
+}
+
+// Acquire implements the Locker interface
+func (l *CloudStorageLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	name := l.objectName(key)
+	// the second attempt follows the removal of an expired lock
+	for attempt := 0; attempt < 2; attempt++ {
+		object := &storage.Object{
+			Name: name,
+			Metadata: map[string]string{
+				lockExpiresMetadataKey: time.Now().Add(ttl).UTC().Format(time.RFC3339Nano),
+			},
+		}
+		created, err := l.service.Objects.Insert(l.bucket, object).
+			IfGenerationMatch(0).
+			Media(strings.NewReader("")).
+			Context(ctx).
+			Do()
+		if err == nil {
+			return strconv.FormatInt(created.Generation, 10), nil
+		}
+		if !isGoogleAPIError(err, http.StatusPreconditionFailed) {
+			return "", fmt.Errorf("unable to create the %s lock: %w", key, err)
+		}
+
+		held, err := l.service.Objects.Get(l.bucket, name).Context(ctx).Do()
+		if isGoogleAPIError(err, http.StatusNotFound) {
+			// released in the meantime
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("unable to get the %s lock: %w", key, err)
+		}
+		expires, err := time.Parse(time.RFC3339Nano, held.Metadata[lockExpiresMetadataKey])
+		if err == nil && time.Now().Before(expires) {
+			return "", ErrLockNotAcquired
+		}
+
+		// only remove the expired lock if it hasn't been taken over by someone else
+		err = l.service.Objects.Delete(l.bucket, name).IfGenerationMatch(held.Generation).Context(ctx).Do()
+		if err != nil && !isGoogleAPIError(err, http.StatusNotFound) && !isGoogleAPIError(err, http.StatusPreconditionFailed) {
+			return "", fmt.Errorf("unable to remove the expired %s lock: %w", key, err)
+		}
+	}
+	return "", ErrLockNotAcquired
+}
+
+// Release implements the Locker interface
+func (l *CloudStorageLocker) Release(ctx context.Context, key string, token string) error {
+	generation, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return fmt.Errorf("the %s lock is not held with the supplied token", key)
+	}
+	err = l.service.Objects.Delete(l.bucket, l.objectName(key)).IfGenerationMatch(generation).Context(ctx).Do()
+	if isGoogleAPIError(err, http.StatusNotFound) || isGoogleAPIError(err, http.StatusPreconditionFailed) {
+		return fmt.Errorf("the %s lock is not held with the supplied token", key)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to release the %s lock: %w", key, err)
+	}
+	return nil
+}
+
+func (l *CloudStorageLocker) objectName(key string) string {
+	return "locks/" + key
+}
+
+func isGoogleAPIError(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
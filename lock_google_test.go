@@ -0,0 +1,22 @@
+package serverutils_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCloudStorageLocker(t *testing.T) {
+	_, err := serverutils.NewCloudStorageLocker(context.Background(), "")
+	assert.NotNil(t, err)
+
+	locker, err := serverutils.NewCloudStorageLocker(context.Background(), "locks-bucket")
+	if err != nil {
+		// without Google credentials the client can't be initialized
+		t.Skipf("unable to initialize the Cloud Storage client: %v", err)
+	}
+
+	assert.NotNil(t, locker.Release(context.Background(), "item-1", "not a generation"))
+}
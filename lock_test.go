@@ -0,0 +1,91 @@
+package serverutils_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	ctx := context.Background()
+	locker := serverutils.NewMemoryLocker()
+
+	token, err := locker.Acquire(ctx, "item-1", time.Minute)
+	assert.Nil(t, err)
+
+	_, err = locker.Acquire(ctx, "item-1", time.Minute)
+	assert.True(t, errors.Is(err, serverutils.ErrLockNotAcquired))
+
+	_, err = locker.Acquire(ctx, "item-2", time.Minute)
+	assert.Nil(t, err)
+
+	assert.NotNil(t, locker.Release(ctx, "item-1", "wrong-token"))
+	assert.Nil(t, locker.Release(ctx, "item-1", token))
+
+	// expired locks can be taken over
+	_, err = locker.Acquire(ctx, "item-3", time.Nanosecond)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = locker.Acquire(ctx, "item-3", time.Minute)
+	assert.Nil(t, err)
+}
+
+func TestMemoryLocker_SweepsExpiredLocks(t *testing.T) {
+	ctx := context.Background()
+	locker := serverutils.NewMemoryLocker()
+
+	token, err := locker.Acquire(ctx, "expired", time.Nanosecond)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+
+	// enough calls to sweep the expired lock
+	for i := 0; i < 1000; i++ {
+		_, err := locker.Acquire(ctx, fmt.Sprintf("item-%d", i), time.Nanosecond)
+		assert.Nil(t, err)
+	}
+	assert.NotNil(t, locker.Release(ctx, "expired", token))
+}
+
+func TestWithLock(t *testing.T) {
+	locker := serverutils.NewMemoryLocker()
+
+	counter := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := serverutils.WithLock(context.Background(), locker, "counter", time.Minute, func(ctx context.Context) error {
+				value := counter
+				time.Sleep(time.Millisecond)
+				counter = value + 1
+				return nil
+			})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, counter)
+
+	err := serverutils.WithLock(context.Background(), locker, "counter", time.Minute, func(ctx context.Context) error {
+		return fmt.Errorf("ka-boom")
+	})
+	assert.NotNil(t, err)
+
+	// the lock was released after the failure
+	release, err := serverutils.Lock(context.Background(), locker, "counter", time.Minute)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = serverutils.Lock(ctx, locker, "counter", time.Minute)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	assert.Nil(t, release())
+}
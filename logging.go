@@ -0,0 +1,98 @@
+package serverutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	loggerMu sync.RWMutex
+	logger   log.FieldLogger = log.StandardLogger()
+)
+
+// Logger returns the logger used by the helpers in this package.
+//
+// It defaults to the logrus standard logger.
+func Logger() log.FieldLogger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return logger
+}
+
+// SetLogger swaps the logger used by the helpers in this package e.g to add default
+// fields or to silence the package in tests
+func SetLogger(l log.FieldLogger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	logger = l
+}
+
+// ConfigureLogging sets up the logrus standard logger to write JSON that Google Cloud
+// Logging understands. Debug messages are logged when debug is turned on in the environment.
+func ConfigureLogging() {
+	log.SetFormatter(&CloudLoggingFormatter{})
+	if IsDebug() {
+		log.SetLevel(log.DebugLevel)
+		return
+	}
+	log.SetLevel(log.InfoLevel)
+}
+
+// cloudLoggingSeverities maps logrus levels to Cloud Logging severities
+var cloudLoggingSeverities = map[log.Level]string{
+	log.TraceLevel: "DEBUG",
+	log.DebugLevel: "DEBUG",
+	log.InfoLevel:  "INFO",
+	log.WarnLevel:  "WARNING",
+	log.ErrorLevel: "ERROR",
+	log.FatalLevel: "CRITICAL",
+	log.PanicLevel: "ALERT",
+}
+
+// CloudLoggingFormatter is a logrus formatter that writes entries as structured JSON
+// understood by Google Cloud Logging.
+//
+// Entries logged with a context (e.g `log.WithContext(ctx)`) that carries an
+// OpenTelemetry span are correlated with their trace. Credentials and PII in the
+// message and string fields are redacted with RedactPII.
+type CloudLoggingFormatter struct{}
+
+// Format implements the logrus Formatter interface
+func (f *CloudLoggingFormatter) Format(entry *log.Entry) ([]byte, error) {
+	data := make(log.Fields, len(entry.Data)+4)
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case error:
+			// errors don't marshal to JSON
+			data[k] = RedactPII(v.Error())
+		case string:
+			data[k] = RedactPII(v)
+		default:
+			data[k] = v
+		}
+	}
+
+	data["severity"] = cloudLoggingSeverities[entry.Level]
+	data["message"] = RedactPII(entry.Message)
+	data["time"] = entry.Time.Format(time.RFC3339Nano)
+
+	if entry.Context != nil {
+		spanContext := trace.SpanContextFromContext(entry.Context)
+		projectID, err := GetEnvVar(GoogleCloudProjectIDEnvVarName)
+		if spanContext.IsValid() && err == nil {
+			data["logging.googleapis.com/trace"] = fmt.Sprintf("projects/%s/traces/%s", projectID, spanContext.TraceID())
+			data["logging.googleapis.com/spanId"] = spanContext.SpanID().String()
+		}
+	}
+
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log entry to JSON: %w", err)
+	}
+	return append(content, '\n'), nil
+}
@@ -0,0 +1,165 @@
+package serverutils_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCloudLoggingFormatter_Format(t *testing.T) {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Nil(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	assert.Nil(t, err)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	tracedCtx := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+	initialProject := os.Getenv(serverutils.GoogleCloudProjectIDEnvVarName)
+	os.Setenv(serverutils.GoogleCloudProjectIDEnvVarName, "test-project")
+	defer os.Setenv(serverutils.GoogleCloudProjectIDEnvVarName, initialProject)
+
+	tests := []struct {
+		name      string
+		entry     *log.Entry
+		wantField map[string]interface{}
+	}{
+		{
+			name: "fields and severity",
+			entry: &log.Entry{
+				Level:   log.WarnLevel,
+				Message: "something happened",
+				Time:    time.Now(),
+				Data:    log.Fields{"project ID": "test", "error": fmt.Errorf("ka-boom")},
+			},
+			wantField: map[string]interface{}{
+				"severity":   "WARNING",
+				"message":    "something happened",
+				"project ID": "test",
+				"error":      "ka-boom",
+			},
+		},
+		{
+			name: "redaction",
+			entry: &log.Entry{
+				Level:   log.InfoLevel,
+				Message: "sent OTP to +254711223344",
+				Time:    time.Now(),
+				Data:    log.Fields{"email": "jane@example.com", "error": fmt.Errorf("invalid token=abc123")},
+			},
+			wantField: map[string]interface{}{
+				"message": "sent OTP to [REDACTED]",
+				"email":   "[REDACTED]",
+				"error":   "invalid token=[REDACTED]",
+			},
+		},
+		{
+			name: "trace correlation",
+			entry: &log.Entry{
+				Level:   log.ErrorLevel,
+				Message: "traced",
+				Time:    time.Now(),
+				Data:    log.Fields{},
+				Context: tracedCtx,
+			},
+			wantField: map[string]interface{}{
+				"severity":                      "ERROR",
+				"logging.googleapis.com/trace":  "projects/test-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+				"logging.googleapis.com/spanId": "00f067aa0ba902b7",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &serverutils.CloudLoggingFormatter{}
+			content, err := f.Format(tt.entry)
+			assert.Nil(t, err)
+
+			var got map[string]interface{}
+			err = json.Unmarshal(content, &got)
+			assert.Nil(t, err)
+			for k, v := range tt.wantField {
+				assert.Equal(t, v, got[k])
+			}
+		})
+	}
+
+	_, err = (&serverutils.CloudLoggingFormatter{}).Format(&log.Entry{
+		Data: log.Fields{"unmarshallable": make(chan string)},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestSetLogger(t *testing.T) {
+	initial := serverutils.Logger()
+	defer serverutils.SetLogger(initial)
+
+	buf := &bytes.Buffer{}
+	l := log.New()
+	l.SetOutput(buf)
+	l.SetFormatter(&serverutils.CloudLoggingFormatter{})
+	serverutils.SetLogger(l)
+
+	serverutils.Logger().WithFields(log.Fields{"port": 8080}).Info("listening")
+	assert.Contains(t, buf.String(), `"message":"listening"`)
+	assert.Contains(t, buf.String(), `"severity":"INFO"`)
+}
+
+func TestMustGetEnvVar_LogsThroughLogger(t *testing.T) {
+	initial := serverutils.Logger()
+	defer serverutils.SetLogger(initial)
+
+	buf := &bytes.Buffer{}
+	l := log.New()
+	l.SetOutput(buf)
+	l.SetFormatter(&serverutils.CloudLoggingFormatter{})
+	serverutils.SetLogger(l)
+
+	setEnv(t, map[string]string{"SERVERUTILS_MISSING_ENV_VAR": ""})
+	defer func() {
+		assert.Equal(t, "mandatory environment variable SERVERUTILS_MISSING_ENV_VAR not found", recover())
+		assert.Contains(t, buf.String(), "mandatory environment variable SERVERUTILS_MISSING_ENV_VAR not found")
+		assert.Contains(t, buf.String(), `"severity":"ERROR"`)
+	}()
+	serverutils.MustGetEnvVar("SERVERUTILS_MISSING_ENV_VAR")
+}
+
+func TestMustGetEnvVar_PanicsWithSilencedLogger(t *testing.T) {
+	initial := serverutils.Logger()
+	defer serverutils.SetLogger(initial)
+
+	l := log.New()
+	l.SetOutput(&bytes.Buffer{})
+	l.SetLevel(log.PanicLevel)
+	serverutils.SetLogger(l)
+
+	setEnv(t, map[string]string{"SERVERUTILS_MISSING_ENV_VAR": ""})
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	serverutils.MustGetEnvVar("SERVERUTILS_MISSING_ENV_VAR")
+	t.Error("MustGetEnvVar did not panic")
+}
+
+func TestConfigureLogging(t *testing.T) {
+	initialDebug := os.Getenv(serverutils.DebugEnvVarName)
+	defer os.Setenv(serverutils.DebugEnvVarName, initialDebug)
+
+	os.Setenv(serverutils.DebugEnvVarName, "true")
+	serverutils.ConfigureLogging()
+	assert.Equal(t, log.DebugLevel, log.GetLevel())
+
+	os.Setenv(serverutils.DebugEnvVarName, "false")
+	serverutils.ConfigureLogging()
+	assert.Equal(t, log.InfoLevel, log.GetLevel())
+
+	log.SetFormatter(&log.TextFormatter{})
+}
@@ -0,0 +1,422 @@
+package serverutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxEmailAttachmentBytes is the size limit of attachments downloaded by EmailAttachmentFromURL
+const MaxEmailAttachmentBytes = 10 << 20
+
+// SMTPTimeout is how long sending an email through an SMTP server may take when the context
+// has no deadline
+const SMTPTimeout = 30 * time.Second
+
+// ErrEmailRecipientsSuppressed is returned when an email is not sent because none of its
+// recipients have verified their address
+var ErrEmailRecipientsSuppressed = errors.New("none of the email recipients have a verified address")
+
+// EmailMessage is an email with a plain text and/or HTML body
+type EmailMessage struct {
+	From        string
+	To          []string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []EmailAttachment
+}
+
+// EmailAttachment is a file attached to an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Mailer sends emails
+type Mailer interface {
+	SendEmail(ctx context.Context, message EmailMessage) error
+}
+
+// EmailSendError is returned by mailers when an email can't be sent.
+//
+// Temporary errors e.g rate limiting or provider outages may succeed if retried.
+type EmailSendError struct {
+	Temporary bool
+	Err       error
+}
+
+// Error implements the error interface
+func (e *EmailSendError) Error() string {
+	return fmt.Sprintf("unable to send email: %v", e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *EmailSendError) Unwrap() error {
+	return e.Err
+}
+
+func validateEmailMessage(message EmailMessage) error {
+	if len(message.To) == 0 {
+		return fmt.Errorf("an email needs at least one recipient")
+	}
+	if message.Text == "" && message.HTML == "" {
+		return fmt.Errorf("an email needs a plain text or HTML body")
+	}
+	for _, address := range append([]string{message.From}, message.To...) {
+		_, err := mail.ParseAddress(address)
+		if err != nil {
+			return fmt.Errorf("invalid email address %q: %w", address, err)
+		}
+	}
+	if strings.ContainsAny(message.Subject, "\r\n") {
+		return fmt.Errorf("the email subject must be a single line")
+	}
+	return nil
+}
+
+// EmailAttachmentFromURL downloads the file at the supplied URL e.g a link to a document in
+// cloud storage, so that it can be attached to an email
+func EmailAttachmentFromURL(ctx context.Context, url string, filename string) (*EmailAttachment, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compose the request for %s: %w", url, err)
+	}
+	client := &http.Client{Timeout: InterserviceRequestTimeout, Transport: NewTracingTransport(nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s: %w", url, err)
+	}
+	defer closeBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download %s: status %d", url, resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, MaxEmailAttachmentBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s: %w", url, err)
+	}
+	if len(content) > MaxEmailAttachmentBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, MaxEmailAttachmentBytes)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	return &EmailAttachment{Filename: filename, ContentType: contentType, Content: content}, nil
+}
+
+type retryingMailer struct {
+	mailer     Mailer
+	maxRetries int
+}
+
+// NewRetryingMailer returns a mailer that retries temporary failures of the supplied
+// mailer with an exponential backoff
+func NewRetryingMailer(mailer Mailer, maxRetries int) Mailer {
+	return &retryingMailer{mailer: mailer, maxRetries: maxRetries}
+}
+
+// SendEmail implements the Mailer interface
+func (m *retryingMailer) SendEmail(ctx context.Context, message EmailMessage) error {
+	for attempt := 0; ; attempt++ {
+		err := m.mailer.SendEmail(ctx, message)
+		var sendErr *EmailSendError
+		if err == nil || !errors.As(err, &sendErr) || !sendErr.Temporary || attempt >= m.maxRetries {
+			return err
+		}
+
+		backoff := time.Duration(1<<attempt) * 100 * time.Millisecond
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// EmailVerifiedFunc reports whether the owner of an email address has verified it
+type EmailVerifiedFunc func(ctx context.Context, address string) (bool, error)
+
+type verifiedRecipientsMailer struct {
+	mailer   Mailer
+	verified EmailVerifiedFunc
+}
+
+// NewVerifiedRecipientsMailer returns a mailer that only sends emails to verified addresses.
+//
+// Unverified recipients are dropped; when none is left ErrEmailRecipientsSuppressed is returned.
+func NewVerifiedRecipientsMailer(mailer Mailer, verified EmailVerifiedFunc) Mailer {
+	return &verifiedRecipientsMailer{mailer: mailer, verified: verified}
+}
+
+// SendEmail implements the Mailer interface
+func (m *verifiedRecipientsMailer) SendEmail(ctx context.Context, message EmailMessage) error {
+	recipients := []string{}
+	for _, address := range message.To {
+		ok, err := m.verified(ctx, address)
+		if err != nil {
+			return fmt.Errorf("unable to check whether %s is verified: %w", address, err)
+		}
+		if ok {
+			recipients = append(recipients, address)
+		}
+	}
+	if len(recipients) == 0 {
+		return ErrEmailRecipientsSuppressed
+	}
+
+	message.To = recipients
+	return m.mailer.SendEmail(ctx, message)
+}
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// NewSMTPMailerFromEnv returns a SMTP mailer configured from the SMTP_* environment variables
+func NewSMTPMailerFromEnv() (*SMTPMailer, error) {
+	host, err := GetEnvVar(SMTPHostEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	portValue, err := GetEnvVar(SMTPPortEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", SMTPPortEnvVarName, err)
+	}
+	return &SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv(SMTPUsernameEnvVarName),
+		Password: os.Getenv(SMTPPasswordEnvVarName),
+	}, nil
+}
+
+// SendEmail implements the Mailer interface
+func (m *SMTPMailer) SendEmail(ctx context.Context, message EmailMessage) error {
+	err := validateEmailMessage(message)
+	if err != nil {
+		return err
+	}
+
+	content, err := buildMIMEMessage(message)
+	if err != nil {
+		return err
+	}
+
+	err = m.send(ctx, message.From, message.To, content)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("unable to send the email: %w", ctxErr)
+	}
+	if err != nil {
+		return &EmailSendError{Temporary: isTemporarySMTPError(err), Err: err}
+	}
+	return nil
+}
+
+// send delivers the message in a single SMTP session that is aborted once ctx is done or,
+// when ctx has no deadline, after SMTPTimeout
+func (m *SMTPMailer) send(ctx context.Context, from string, to []string, content []byte) error {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.Host, strconv.Itoa(m.Port)))
+	if err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		err = conn.SetDeadline(time.Now().Add(SMTPTimeout))
+		if err != nil {
+			closeSMTPConnection(conn)
+			return err
+		}
+	}
+
+	// unblock the session once ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		closeSMTPConnection(conn)
+		return err
+	}
+	defer closeSMTPConnection(client)
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: m.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("the SMTP server doesn't support authentication")
+		}
+		err = client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(from)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(content)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// closeSMTPConnection closes a SMTP connection and logs the failure, unless the connection
+// was already closed e.g by QUIT
+func closeSMTPConnection(conn io.Closer) {
+	err := conn.Close()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		Logger().Println(err)
+	}
+}
+
+// isTemporarySMTPError reports whether the server could not be reached or replied with a
+// transient (4xx) failure
+func isTemporarySMTPError(err error) bool {
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) {
+		return protocolErr.Code >= 400 && protocolErr.Code < 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// buildMIMEMessage composes a multipart/mixed message whose first part holds the plain text
+// and HTML alternatives of the body, followed by the attachments
+func buildMIMEMessage(message EmailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", message.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(message.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	var body bytes.Buffer
+	alternatives := multipart.NewWriter(&body)
+	for _, alternative := range []struct{ contentType, body string }{
+		{"text/plain", message.Text},
+		{"text/html", message.HTML},
+	} {
+		if alternative.body == "" {
+			continue
+		}
+		part, err := alternatives.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = writeBase64Lines(part, []byte(alternative.body))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := alternatives.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	bodyPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternatives.Boundary()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = bodyPart.Write(body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range message.Attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = writeBase64Lines(part, attachment.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines writes base64 encoded content in lines of 76 characters as required by RFC 2045
+func writeBase64Lines(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 76 {
+		_, err := fmt.Fprintf(w, "%s\r\n", encoded[:76])
+		if err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := fmt.Fprintf(w, "%s\r\n", encoded)
+	return err
+}
@@ -0,0 +1,235 @@
+package serverutils_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMailer struct {
+	errs []error
+	sent []serverutils.EmailMessage
+}
+
+func (m *fakeMailer) SendEmail(ctx context.Context, message serverutils.EmailMessage) error {
+	m.sent = append(m.sent, message)
+	if len(m.errs) == 0 {
+		return nil
+	}
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+	return err
+}
+
+func TestNewRetryingMailer(t *testing.T) {
+	temporary := &serverutils.EmailSendError{Temporary: true, Err: fmt.Errorf("unavailable")}
+	permanent := &serverutils.EmailSendError{Err: fmt.Errorf("bad request")}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   bool
+		wantSends int
+	}{
+		{name: "success", wantSends: 1},
+		{name: "temporary failure recovers", errs: []error{temporary, temporary}, wantSends: 3},
+		{name: "permanent failure", errs: []error{permanent}, wantErr: true, wantSends: 1},
+		{name: "retries exhausted", errs: []error{temporary, temporary, temporary}, wantErr: true, wantSends: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mailer := &fakeMailer{errs: tt.errs}
+			err := serverutils.NewRetryingMailer(mailer, 2).SendEmail(context.Background(), serverutils.EmailMessage{})
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Len(t, mailer.sent, tt.wantSends)
+		})
+	}
+}
+
+func TestNewVerifiedRecipientsMailer(t *testing.T) {
+	verified := func(ctx context.Context, address string) (bool, error) {
+		if address == "broken@example.com" {
+			return false, fmt.Errorf("lookup failed")
+		}
+		return address == "verified@example.com", nil
+	}
+
+	mailer := &fakeMailer{}
+	suppressing := serverutils.NewVerifiedRecipientsMailer(mailer, verified)
+
+	err := suppressing.SendEmail(context.Background(), serverutils.EmailMessage{
+		To: []string{"verified@example.com", "unverified@example.com"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"verified@example.com"}, mailer.sent[0].To)
+
+	err = suppressing.SendEmail(context.Background(), serverutils.EmailMessage{To: []string{"unverified@example.com"}})
+	assert.True(t, errors.Is(err, serverutils.ErrEmailRecipientsSuppressed))
+
+	err = suppressing.SendEmail(context.Background(), serverutils.EmailMessage{To: []string{"broken@example.com"}})
+	assert.NotNil(t, err)
+	assert.Len(t, mailer.sent, 1)
+}
+
+func TestSMTPMailer_SendEmail(t *testing.T) {
+	// nothing listens on the port so the failure is temporary
+	mailer := &serverutils.SMTPMailer{Host: "127.0.0.1", Port: freePort(t)}
+
+	err := mailer.SendEmail(context.Background(), serverutils.EmailMessage{
+		From:    "noreply@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Test",
+		Text:    "Hello",
+	})
+	var sendErr *serverutils.EmailSendError
+	assert.True(t, errors.As(err, &sendErr))
+	assert.True(t, sendErr.Temporary)
+
+	err = mailer.SendEmail(context.Background(), serverutils.EmailMessage{
+		From: "noreply@example.com",
+		To:   []string{"user@example.com"},
+	})
+	assert.NotNil(t, err)
+}
+
+// serveSMTP accepts a single SMTP session and returns the message it received
+func serveSMTP(t *testing.T, listener net.Listener) <-chan string {
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		reply := func(line string) {
+			assert.Nil(t, text.PrintfLine("%s", line))
+		}
+		reply("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+			case "EHLO":
+				reply("250 localhost")
+			case "DATA":
+				reply("354 go ahead")
+				content, err := text.ReadDotBytes()
+				assert.Nil(t, err)
+				received <- string(content)
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return received
+}
+
+func TestSMTPMailer_SendEmail_Delivers(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	received := serveSMTP(t, listener)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	mailer := &serverutils.SMTPMailer{Host: "127.0.0.1", Port: port}
+	err = mailer.SendEmail(context.Background(), serverutils.EmailMessage{
+		From:    "noreply@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Test",
+		Text:    "Hello",
+	})
+	assert.Nil(t, err)
+
+	select {
+	case content := <-received:
+		assert.Contains(t, content, "Subject: Test")
+		assert.Contains(t, content, "To: user@example.com")
+	case <-time.After(time.Second):
+		t.Error("the SMTP server did not receive the message")
+	}
+}
+
+func TestSMTPMailer_SendEmail_HonoursContext(t *testing.T) {
+	// the server accepts the connection but never greets the client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			_, _ = io.Copy(io.Discard, conn)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	mailer := &serverutils.SMTPMailer{Host: "127.0.0.1", Port: port}
+	start := time.Now()
+	err = mailer.SendEmail(ctx, serverutils.EmailMessage{
+		From:    "noreply@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Test",
+		Text:    "Hello",
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < 2*time.Second)
+}
+
+func TestNewSMTPMailerFromEnv(t *testing.T) {
+	setEnv(t, map[string]string{
+		serverutils.SMTPHostEnvVarName:     "smtp.example.com",
+		serverutils.SMTPPortEnvVarName:     "587",
+		serverutils.SMTPUsernameEnvVarName: "user",
+	})
+	mailer, err := serverutils.NewSMTPMailerFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, 587, mailer.Port)
+	assert.Equal(t, "user", mailer.Username)
+
+	setEnv(t, map[string]string{serverutils.SMTPPortEnvVarName: "not-a-port"})
+	_, err = serverutils.NewSMTPMailerFromEnv()
+	assert.NotNil(t, err)
+}
+
+func TestEmailAttachmentFromURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, err := w.Write([]byte("%PDF-1.4"))
+		assert.Nil(t, err)
+	}))
+	defer srv.Close()
+
+	attachment, err := serverutils.EmailAttachmentFromURL(context.Background(), srv.URL+"/report.pdf", "report.pdf")
+	assert.Nil(t, err)
+	assert.Equal(t, "application/pdf", attachment.ContentType)
+	assert.Equal(t, []byte("%PDF-1.4"), attachment.Content)
+
+	_, err = serverutils.EmailAttachmentFromURL(context.Background(), srv.URL+"/missing", "missing.pdf")
+	assert.NotNil(t, err)
+}
@@ -125,9 +125,120 @@ var (
 	}
 )
 
+// Outbound HTTP measures used to record metrics for calls made to upstream APIs
+var (
+	OutboundRequestLatency = stats.Float64(
+		"outbound_request_latency",
+		"The Latency in milliseconds per outbound http request",
+		"ms",
+	)
+
+	// OutboundHost is the host of the upstream API that was called
+	OutboundHost = tag.MustNewKey("outbound.host")
+
+	// OutboundMethod is the HTTP method of the outbound request
+	OutboundMethod = tag.MustNewKey("outbound.method")
+
+	// OutboundStatusClass is the class of the response status code e.g 2xx, 4xx, 5xx.
+	// Transport level failures are recorded as "error"
+	OutboundStatusClass = tag.MustNewKey("outbound.status_class")
+
+	OutboundRequestLatencyView = &view.View{
+		Name:        "outbound_request_latency_distribution",
+		Description: "Time taken by an outbound http request",
+		Measure:     OutboundRequestLatency,
+		Aggregation: view.Distribution(LatencyBounds...),
+		TagKeys:     []tag.Key{OutboundHost, OutboundMethod, OutboundStatusClass},
+	}
+
+	OutboundRequestCountView = &view.View{
+		Name:        "outbound_request_count",
+		Description: "The number of outbound http requests",
+		Measure:     OutboundRequestLatency,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{OutboundHost, OutboundMethod, OutboundStatusClass},
+	}
+)
+
+// Circuit breaker measures used to record the state of the circuits of upstream hosts
+var (
+	CircuitBreakerStateChange = stats.Int64(
+		"circuit_breaker_state_change",
+		"The state a circuit changed to: 0 closed, 1 half-open, 2 open",
+		"1",
+	)
+
+	// CircuitBreakerStateKey is the state a circuit changed to e.g open, half-open, closed
+	CircuitBreakerStateKey = tag.MustNewKey("circuit.state")
+
+	CircuitBreakerStateView = &view.View{
+		Name:        "circuit_breaker_state",
+		Description: "The current state of the circuit of an upstream host: 0 closed, 1 half-open, 2 open",
+		Measure:     CircuitBreakerStateChange,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{OutboundHost},
+	}
+
+	CircuitBreakerStateChangeCountView = &view.View{
+		Name:        "circuit_breaker_state_change_count",
+		Description: "The number of times the circuits of upstream hosts changed state",
+		Measure:     CircuitBreakerStateChange,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{OutboundHost, CircuitBreakerStateKey},
+	}
+)
+
+// Auth flow measures used to record login and token refresh metrics
+var (
+	AuthFlowLatency = stats.Float64(
+		"auth_flow_latency",
+		"The Latency in milliseconds per login or token refresh",
+		"ms",
+	)
+
+	// AuthFlowName is the auth flow being recorded e.g login, refresh
+	AuthFlowName = tag.MustNewKey("auth.flow")
+
+	// AuthFlowStatus is used to tag whether the flow passed or failed
+	AuthFlowStatus = tag.MustNewKey("auth.status")
+
+	AuthFlowCountView = &view.View{
+		Name:        "auth_flow_count",
+		Description: "The number of logins and token refreshes",
+		Measure:     AuthFlowLatency,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{AuthFlowName, AuthFlowStatus},
+	}
+
+	AuthFlowLatencyView = &view.View{
+		Name:        "auth_flow_latency_distribution",
+		Description: "Time taken by a login or token refresh",
+		Measure:     AuthFlowLatency,
+		Aggregation: view.Distribution(LatencyBounds...),
+		TagKeys:     []tag.Key{AuthFlowName, AuthFlowStatus},
+	}
+)
+
+// Auth flow names
+const (
+	AuthFlowLogin   = "login"
+	AuthFlowRefresh = "refresh"
+)
+
 // DefaultServiceViews are the default/common server views provided by base package
 // The views can be used by the various services
-var DefaultServiceViews = []*view.View{GraphqlResolverLatencyView, GraphqlResolverCountView, ServerRequestLatencyView, ServerRequestCountView}
+var DefaultServiceViews = []*view.View{
+	GraphqlResolverLatencyView,
+	GraphqlResolverCountView,
+	ServerRequestLatencyView,
+	ServerRequestCountView,
+	OutboundRequestLatencyView,
+	OutboundRequestCountView,
+	AuthFlowCountView,
+	AuthFlowLatencyView,
+	CircuitBreakerStateView,
+	CircuitBreakerStateChangeCountView,
+}
 
 // GetRunningEnvironment returns the environment where the service is running. Important
 // so as to point to the correct deps
@@ -249,6 +360,72 @@ func RecordHTTPStats(w *MetricsResponseWriter, r *http.Request) {
 	stats.Record(ctx, HTTPRequestLatency.M(latency))
 }
 
+// RecordAuthFlowMetrics records the metrics for a login or token refresh.
+// It should be deferred until the login/refresh is completed
+func RecordAuthFlowMetrics(ctx context.Context, startTime time.Time, flow string, e error) {
+	status := ResolverSuccessValue
+	if e != nil {
+		status = ResolverFailureValue
+	}
+
+	ctx, _ = tag.New(ctx,
+		tag.Insert(AuthFlowName, flow),
+		tag.Insert(AuthFlowStatus, status),
+	)
+
+	// duration is in nanoseconds (ns)
+	// 1ms = 1000000 ns
+	latency := float64(time.Since(startTime) / 1000000)
+
+	stats.Record(ctx, AuthFlowLatency.M(latency))
+}
+
+// StatusClass returns the class of a HTTP status code e.g 2xx, 4xx
+func StatusClass(code int) string {
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+// MetricsTransport is a http.RoundTripper that records the outbound request metrics
+// for every call made to an upstream API
+type MetricsTransport struct {
+	base http.RoundTripper
+}
+
+// NewMetricsTransport wraps the supplied transport so that outbound requests are measured.
+// When the supplied transport is nil, http.DefaultTransport is used.
+func NewMetricsTransport(base http.RoundTripper) *MetricsTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &MetricsTransport{base: base}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *MetricsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	startTime := time.Now()
+
+	resp, err := t.base.RoundTrip(r)
+
+	statusClass := "error"
+	if err == nil {
+		statusClass = StatusClass(resp.StatusCode)
+	}
+
+	ctx, _ := tag.New(r.Context(),
+		tag.Insert(OutboundHost, r.URL.Host),
+		tag.Insert(OutboundMethod, r.Method),
+		tag.Insert(OutboundStatusClass, statusClass),
+	)
+
+	// duration is in nanoseconds (ns)
+	// 1ms = 1000000 ns
+	latency := float64(time.Since(startTime) / 1000000)
+
+	stats.Record(ctx, OutboundRequestLatency.M(latency))
+
+	return resp, err
+}
+
 // MetricsResponseWriter implements the http.ResponseWriter Interface
 // it is a wrapper of http.ResponseWriter and enables obtaining measures
 type MetricsResponseWriter struct {
@@ -285,7 +462,7 @@ func (m *MetricsResponseWriter) Write(b []byte) (int, error) {
 // about the service deployment.
 func InitOtelSDK(ctx context.Context, serviceName string) (*tracesdk.TracerProvider, error) {
 	// Jaeger Exporter initialization
-	jaegerURL := MustGetEnvVar("JAEGER_URL")
+	jaegerURL := MustGetEnvVar(JaegerURLEnvVarName)
 
 	exporter, err := jaeger.New(
 		jaeger.WithCollectorEndpoint(
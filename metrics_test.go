@@ -12,6 +12,9 @@ import (
 	"time"
 
 	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 )
 
@@ -406,3 +409,109 @@ func TestInitOtelSDK(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordAuthFlowMetrics(t *testing.T) {
+	// start from an empty view so that rows recorded elsewhere are not counted
+	view.Unregister(serverutils.AuthFlowCountView)
+	err := view.Register(serverutils.AuthFlowCountView)
+	assert.Nil(t, err)
+	defer view.Unregister(serverutils.AuthFlowCountView)
+
+	type args struct {
+		ctx       context.Context
+		startTime time.Time
+		flow      string
+		e         error
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantStatus string
+	}{
+		{
+			name: "success: login ok",
+			args: args{
+				ctx:       context.Background(),
+				startTime: time.Now(),
+				flow:      serverutils.AuthFlowLogin,
+			},
+			wantStatus: serverutils.ResolverSuccessValue,
+		},
+		{
+			name: "success: failed refresh",
+			args: args{
+				ctx:       context.Background(),
+				startTime: time.Now(),
+				flow:      serverutils.AuthFlowRefresh,
+				e:         fmt.Errorf("invalid refresh token"),
+			},
+			wantStatus: serverutils.ResolverFailureValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverutils.RecordAuthFlowMetrics(tt.args.ctx, tt.args.startTime, tt.args.flow, tt.args.e)
+
+			rows, err := view.RetrieveData(serverutils.AuthFlowCountView.Name)
+			assert.Nil(t, err)
+
+			want := []tag.Tag{
+				{Key: serverutils.AuthFlowName, Value: tt.args.flow},
+				{Key: serverutils.AuthFlowStatus, Value: tt.wantStatus},
+			}
+			var count int64
+			for _, row := range rows {
+				if reflect.DeepEqual(row.Tags, want) {
+					count = row.Data.(*view.CountData).Value
+				}
+			}
+			assert.Equal(t, int64(1), count)
+		})
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		want string
+	}{
+		{
+			name: "success",
+			code: http.StatusCreated,
+			want: "2xx",
+		},
+		{
+			name: "client error",
+			code: http.StatusNotFound,
+			want: "4xx",
+		},
+		{
+			name: "server error",
+			code: http.StatusBadGateway,
+			want: "5xx",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, serverutils.StatusClass(tt.code))
+		})
+	}
+}
+
+func TestMetricsTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: serverutils.NewMetricsTransport(nil)}
+
+	resp, err := client.Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+	assert.Nil(t, resp.Body.Close())
+
+	_, err = client.Get("http://127.0.0.1:0/unreachable")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,226 @@
+package serverutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// OTP defaults
+const (
+	// DefaultOTPLength is the number of digits of generated OTPs
+	DefaultOTPLength = 6
+
+	// DefaultOTPTTL is how long an OTP is valid for
+	DefaultOTPTTL = 5 * time.Minute
+
+	// DefaultOTPMaxAttempts is the number of verification attempts allowed per OTP
+	DefaultOTPMaxAttempts = 5
+)
+
+// OTP verification errors
+var (
+	ErrInvalidOTP          = errors.New("the OTP is invalid")
+	ErrOTPExpired          = errors.New("the OTP has expired")
+	ErrOTPAttemptsExceeded = errors.New("too many attempts to verify the OTP")
+)
+
+// OTPRecord is a stored OTP. Only a salted hash of the code is kept.
+type OTPRecord struct {
+	Hash      []byte
+	Salt      []byte
+	ExpiresAt time.Time
+	Attempts  int
+}
+
+// OTPStore keeps the OTPs issued to phone numbers (or any other identifier).
+//
+// Saving an OTP replaces any previous one but carries over the attempts of a previous OTP that
+// has not expired, so that requesting a new OTP does not reset the attempt limit.
+// Attempt atomically increments the attempts of the stored OTP and returns it, or returns
+// nil when there is no OTP for the key. Consume atomically deletes the OTP if its hash is
+// still the supplied one and reports whether it did, so that an OTP is only used once.
+type OTPStore interface {
+	Save(ctx context.Context, key string, record OTPRecord) error
+	Attempt(ctx context.Context, key string) (*OTPRecord, error)
+	Consume(ctx context.Context, key string, hash []byte) (bool, error)
+}
+
+// OTPService issues and verifies one time passwords
+type OTPService struct {
+	store       OTPStore
+	length      int
+	ttl         time.Duration
+	maxAttempts int
+}
+
+// NewOTPService returns an OTP service with the default length, validity and attempt limit
+func NewOTPService(store OTPStore) *OTPService {
+	return &OTPService{
+		store:       store,
+		length:      DefaultOTPLength,
+		ttl:         DefaultOTPTTL,
+		maxAttempts: DefaultOTPMaxAttempts,
+	}
+}
+
+// WithLimits changes the length, validity and attempt limit of the OTPs issued by the service
+func (s *OTPService) WithLimits(length int, ttl time.Duration, maxAttempts int) *OTPService {
+	s.length = length
+	s.ttl = ttl
+	s.maxAttempts = maxAttempts
+	return s
+}
+
+// Generate issues a new OTP for the phone number, replacing any previous one.
+// The returned code should be sent to the user and not stored.
+//
+// Failed attempts to verify a previous OTP that has not expired count against the new one.
+func (s *OTPService) Generate(ctx context.Context, phone string) (string, error) {
+	code, err := GenerateNumericCode(s.length)
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, 16)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate an OTP salt: %w", err)
+	}
+
+	err = s.store.Save(ctx, phone, OTPRecord{
+		Hash:      hashOTP(salt, code),
+		Salt:      salt,
+		ExpiresAt: time.Now().Add(s.ttl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to save the OTP: %w", err)
+	}
+	return code, nil
+}
+
+// Verify checks the OTP supplied for the phone number.
+//
+// It returns nil when the OTP is valid, after which the OTP can't be used again.
+// Otherwise it returns ErrInvalidOTP, ErrOTPExpired, ErrOTPAttemptsExceeded or a store error.
+func (s *OTPService) Verify(ctx context.Context, phone string, code string) error {
+	record, err := s.store.Attempt(ctx, phone)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the OTP: %w", err)
+	}
+	if record == nil {
+		return ErrInvalidOTP
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return ErrOTPExpired
+	}
+	if record.Attempts > s.maxAttempts {
+		return ErrOTPAttemptsExceeded
+	}
+	if subtle.ConstantTimeCompare(hashOTP(record.Salt, code), record.Hash) != 1 {
+		return ErrInvalidOTP
+	}
+
+	consumed, err := s.store.Consume(ctx, phone, record.Hash)
+	if err != nil {
+		return fmt.Errorf("unable to consume the OTP: %w", err)
+	}
+	if !consumed {
+		// the OTP was used or replaced by a concurrent request
+		return ErrInvalidOTP
+	}
+	return nil
+}
+
+// GenerateNumericCode returns a cryptographically random code of the supplied number of digits
+func GenerateNumericCode(length int) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("invalid code length %d", length)
+	}
+	upper := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, upper)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate a random code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+func hashOTP(salt []byte, code string) []byte {
+	hash := sha256.Sum256(append(append([]byte{}, salt...), code...))
+	return hash[:]
+}
+
+// MemoryOTPStore is an in-memory OTPStore.
+//
+// It is suitable for a single instance of a service; OTPs are not shared across instances.
+type MemoryOTPStore struct {
+	mu      sync.Mutex
+	records map[string]*OTPRecord
+	calls   int
+}
+
+// NewMemoryOTPStore returns an initialized in-memory OTP store
+func NewMemoryOTPStore() *MemoryOTPStore {
+	return &MemoryOTPStore{records: make(map[string]*OTPRecord)}
+}
+
+// Save implements the OTPStore interface
+func (s *MemoryOTPStore) Save(ctx context.Context, key string, record OTPRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls%otpSweepInterval == 0 {
+		s.sweep(time.Now())
+	}
+
+	if previous, ok := s.records[key]; ok && !time.Now().After(previous.ExpiresAt) {
+		record.Attempts += previous.Attempts
+	}
+	s.records[key] = &record
+	return nil
+}
+
+// number of calls to Save between sweeps of expired OTPs
+const otpSweepInterval = 1000
+
+// Attempt implements the OTPStore interface
+func (s *MemoryOTPStore) Attempt(ctx context.Context, key string) (*OTPRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return nil, nil
+	}
+	record.Attempts++
+	attempted := *record
+	return &attempted, nil
+}
+
+// Consume implements the OTPStore interface
+func (s *MemoryOTPStore) Consume(ctx context.Context, key string, hash []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok || subtle.ConstantTimeCompare(record.Hash, hash) != 1 {
+		return false, nil
+	}
+	delete(s.records, key)
+	return true, nil
+}
+
+func (s *MemoryOTPStore) sweep(now time.Time) {
+	for key, record := range s.records {
+		if now.After(record.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
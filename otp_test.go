@@ -0,0 +1,142 @@
+package serverutils_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateNumericCode(t *testing.T) {
+	for _, length := range []int{1, 4, 6, 10} {
+		code, err := serverutils.GenerateNumericCode(length)
+		assert.Nil(t, err)
+		assert.Len(t, code, length)
+		for _, r := range code {
+			assert.True(t, r >= '0' && r <= '9')
+		}
+	}
+
+	_, err := serverutils.GenerateNumericCode(0)
+	assert.NotNil(t, err)
+}
+
+func TestOTPService(t *testing.T) {
+	ctx := context.Background()
+	const phone = "+254711223344"
+
+	tests := []struct {
+		name    string
+		verify  func(s *serverutils.OTPService, code string) error
+		wantErr error
+	}{
+		{
+			name: "valid OTP",
+			verify: func(s *serverutils.OTPService, code string) error {
+				return s.Verify(ctx, phone, code)
+			},
+		},
+		{
+			name: "OTP can only be used once",
+			verify: func(s *serverutils.OTPService, code string) error {
+				assert.Nil(t, s.Verify(ctx, phone, code))
+				return s.Verify(ctx, phone, code)
+			},
+			wantErr: serverutils.ErrInvalidOTP,
+		},
+		{
+			name: "wrong OTP",
+			verify: func(s *serverutils.OTPService, code string) error {
+				return s.Verify(ctx, phone, "wrong")
+			},
+			wantErr: serverutils.ErrInvalidOTP,
+		},
+		{
+			name: "OTP of another phone",
+			verify: func(s *serverutils.OTPService, code string) error {
+				return s.Verify(ctx, "+254700000000", code)
+			},
+			wantErr: serverutils.ErrInvalidOTP,
+		},
+		{
+			name: "too many attempts",
+			verify: func(s *serverutils.OTPService, code string) error {
+				for i := 0; i < 3; i++ {
+					assert.True(t, errors.Is(s.Verify(ctx, phone, "wrong"), serverutils.ErrInvalidOTP))
+				}
+				return s.Verify(ctx, phone, code)
+			},
+			wantErr: serverutils.ErrOTPAttemptsExceeded,
+		},
+		{
+			name: "a new OTP does not reset the attempts",
+			verify: func(s *serverutils.OTPService, code string) error {
+				for i := 0; i < 3; i++ {
+					assert.True(t, errors.Is(s.Verify(ctx, phone, "wrong"), serverutils.ErrInvalidOTP))
+				}
+				code, err := s.Generate(ctx, phone)
+				assert.Nil(t, err)
+				return s.Verify(ctx, phone, code)
+			},
+			wantErr: serverutils.ErrOTPAttemptsExceeded,
+		},
+		{
+			name: "expired",
+			verify: func(s *serverutils.OTPService, code string) error {
+				s.WithLimits(6, time.Nanosecond, 3)
+				code, err := s.Generate(ctx, phone)
+				assert.Nil(t, err)
+				time.Sleep(time.Millisecond)
+				return s.Verify(ctx, phone, code)
+			},
+			wantErr: serverutils.ErrOTPExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := serverutils.NewOTPService(serverutils.NewMemoryOTPStore()).WithLimits(6, time.Minute, 3)
+			code, err := service.Generate(ctx, phone)
+			assert.Nil(t, err)
+			assert.Len(t, code, 6)
+
+			err = tt.verify(service, code)
+			if tt.wantErr == nil {
+				assert.Nil(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.wantErr))
+		})
+	}
+}
+
+func TestOTPService_ParallelVerify(t *testing.T) {
+	ctx := context.Background()
+	const phone = "+254711223344"
+
+	service := serverutils.NewOTPService(serverutils.NewMemoryOTPStore()).WithLimits(6, time.Minute, 20)
+	code, err := service.Generate(ctx, phone)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	verified := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if service.Verify(ctx, phone, code) == nil {
+				mu.Lock()
+				verified++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the OTP can only be used once
+	assert.Equal(t, 1, verified)
+}
@@ -0,0 +1,308 @@
+package serverutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PIN defaults
+const (
+	// MinPINLength is the minimum number of digits of a PIN
+	MinPINLength = 4
+
+	// MaxPINLength is the maximum number of digits of a PIN
+	MaxPINLength = 8
+
+	// DefaultPINMaxAttempts is the number of failed verifications after which a PIN is locked
+	DefaultPINMaxAttempts = 3
+
+	// DefaultPINLockout is how long a PIN stays locked after too many failed verifications
+	DefaultPINLockout = 15 * time.Minute
+
+	// DefaultPINHashCost is the bcrypt cost used to hash PINs
+	DefaultPINHashCost = 12
+
+	// MaxPINHashCost is the highest bcrypt cost accepted when hashing or comparing PINs
+	MaxPINHashCost = 16
+)
+
+// PIN errors
+var (
+	ErrPINNotSet  = errors.New("no PIN has been set")
+	ErrInvalidPIN = errors.New("the PIN is invalid")
+	ErrPINLocked  = errors.New("the PIN is locked after too many failed attempts")
+)
+
+// commonly used PINs that are easy to guess
+var weakPINs = map[string]bool{
+	"1212": true, "2580": true, "1004": true, "2000": true, "6969": true, "1122": true,
+	"121212": true, "112233": true, "123123": true, "696969": true, "159753": true,
+}
+
+// ValidatePINStrength checks that a PIN is made up of MinPINLength to MaxPINLength digits
+// and is not easy to guess e.g repeated (1111) or sequential (1234, 9876) digits
+func ValidatePINStrength(pin string) error {
+	if len(pin) < MinPINLength || len(pin) > MaxPINLength {
+		return fmt.Errorf("a PIN must have between %d and %d digits", MinPINLength, MaxPINLength)
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("a PIN must only contain digits")
+		}
+	}
+
+	repeated, ascending, descending := true, true, true
+	for i := 1; i < len(pin); i++ {
+		repeated = repeated && pin[i] == pin[0]
+		ascending = ascending && pin[i] == pin[i-1]+1
+		descending = descending && pin[i] == pin[i-1]-1
+	}
+	if repeated || ascending || descending || weakPINs[pin] {
+		return fmt.Errorf("the PIN is too easy to guess")
+	}
+	return nil
+}
+
+// HashPIN returns a salted bcrypt hash of the PIN with the supplied cost
+func HashPIN(pin string, cost int) (string, error) {
+	if cost < bcrypt.MinCost || cost > MaxPINHashCost {
+		return "", fmt.Errorf("the PIN hash cost must be between %d and %d", bcrypt.MinCost, MaxPINHashCost)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), cost)
+	if err != nil {
+		return "", fmt.Errorf("unable to hash the PIN: %w", err)
+	}
+	return string(hash), nil
+}
+
+// ComparePINHash reports whether the PIN matches a hash created by HashPIN.
+// Hashes with a cost above MaxPINHashCost are rejected rather than computed.
+func ComparePINHash(hash string, pin string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil || cost > MaxPINHashCost {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin)) == nil
+}
+
+// PINRecord is a user's stored PIN
+type PINRecord struct {
+	Hash           string
+	FailedAttempts int
+	LockedUntil    time.Time
+}
+
+// PINStore keeps the PINs of users. GetPIN returns nil when the user has not set a PIN.
+//
+// AttemptPIN atomically records an attempt to verify a PIN, like OTPStore.Attempt, and returns
+// the record as it was before the attempt, or nil when the user has not set a PIN. Attempts on
+// a locked PIN don't change it. Otherwise the failed attempts are incremented and, once they
+// reach maxAttempts, the PIN is locked for the lockout duration and the count starts over.
+// ResetPINAttempts atomically clears the failed attempts and any lockout.
+type PINStore interface {
+	GetPIN(ctx context.Context, userID string) (*PINRecord, error)
+	SavePIN(ctx context.Context, userID string, record PINRecord) error
+	AttemptPIN(ctx context.Context, userID string, maxAttempts int, lockout time.Duration) (*PINRecord, error)
+	ResetPINAttempts(ctx context.Context, userID string) error
+}
+
+// PINService sets, verifies and resets user PINs.
+//
+// A PIN is locked for a while after too many failed verifications. Forgotten PINs
+// are reset after verifying an OTP sent to the user's phone (see RequestPINReset).
+type PINService struct {
+	store       PINStore
+	otp         *OTPService
+	maxAttempts int
+	lockout     time.Duration
+	hashCost    int
+}
+
+// NewPINService returns a PIN service with the default attempt limit, lockout and hashing cost
+func NewPINService(store PINStore, otp *OTPService) *PINService {
+	return &PINService{
+		store:       store,
+		otp:         otp,
+		maxAttempts: DefaultPINMaxAttempts,
+		lockout:     DefaultPINLockout,
+		hashCost:    DefaultPINHashCost,
+	}
+}
+
+// WithLimits changes the attempt limit, lockout duration and bcrypt hashing cost of the service
+func (s *PINService) WithLimits(maxAttempts int, lockout time.Duration, hashCost int) *PINService {
+	s.maxAttempts = maxAttempts
+	s.lockout = lockout
+	s.hashCost = hashCost
+	return s
+}
+
+// SetPIN sets the PIN of a user who does not have one yet
+func (s *PINService) SetPIN(ctx context.Context, userID string, pin string) error {
+	record, err := s.store.GetPIN(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the PIN: %w", err)
+	}
+	if record != nil {
+		return fmt.Errorf("a PIN has already been set")
+	}
+	return s.savePIN(ctx, userID, pin)
+}
+
+// VerifyPIN checks the PIN supplied by a user.
+//
+// It returns nil when the PIN is valid. Otherwise it returns ErrPINNotSet, ErrInvalidPIN,
+// ErrPINLocked or a store error.
+func (s *PINService) VerifyPIN(ctx context.Context, userID string, pin string) error {
+	// the attempt is counted before the PIN is compared so that parallel guesses can't get
+	// around the attempt limit
+	record, err := s.store.AttemptPIN(ctx, userID, s.maxAttempts, s.lockout)
+	if err != nil {
+		return fmt.Errorf("unable to record the PIN attempt: %w", err)
+	}
+	if record == nil {
+		return ErrPINNotSet
+	}
+	if time.Now().Before(record.LockedUntil) {
+		return ErrPINLocked
+	}
+
+	if ComparePINHash(record.Hash, pin) {
+		err = s.store.ResetPINAttempts(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("unable to reset the PIN attempts: %w", err)
+		}
+		return nil
+	}
+	if record.FailedAttempts+1 >= s.maxAttempts {
+		return ErrPINLocked
+	}
+	return ErrInvalidPIN
+}
+
+// ChangePIN replaces a user's PIN after verifying the current one
+func (s *PINService) ChangePIN(ctx context.Context, userID string, currentPIN string, newPIN string) error {
+	err := s.VerifyPIN(ctx, userID, currentPIN)
+	if err != nil {
+		return err
+	}
+	return s.savePIN(ctx, userID, newPIN)
+}
+
+// RequestPINReset issues the OTP that authorises resetting a user's forgotten PIN.
+//
+// The OTP is tied to the user, not to a phone number, so the caller must send the returned
+// code to the phone number on record for the user, never to one supplied in the request.
+func (s *PINService) RequestPINReset(ctx context.Context, userID string) (string, error) {
+	return s.otp.Generate(ctx, pinResetOTPKey(userID))
+}
+
+// ResetPIN replaces a forgotten PIN after verifying the OTP issued by RequestPINReset for
+// the same user. It also lifts any lockout.
+func (s *PINService) ResetPIN(ctx context.Context, userID string, otp string, newPIN string) error {
+	err := ValidatePINStrength(newPIN)
+	if err != nil {
+		return err
+	}
+	err = s.otp.Verify(ctx, pinResetOTPKey(userID), otp)
+	if err != nil {
+		return err
+	}
+	return s.savePIN(ctx, userID, newPIN)
+}
+
+func pinResetOTPKey(userID string) string {
+	return "pin-reset:" + userID
+}
+
+func (s *PINService) savePIN(ctx context.Context, userID string, pin string) error {
+	err := ValidatePINStrength(pin)
+	if err != nil {
+		return err
+	}
+	hash, err := HashPIN(pin, s.hashCost)
+	if err != nil {
+		return err
+	}
+	err = s.store.SavePIN(ctx, userID, PINRecord{Hash: hash})
+	if err != nil {
+		return fmt.Errorf("unable to save the PIN: %w", err)
+	}
+	return nil
+}
+
+// MemoryPINStore is an in-memory PINStore, mostly useful in tests
+type MemoryPINStore struct {
+	mu      sync.Mutex
+	records map[string]PINRecord
+}
+
+// NewMemoryPINStore returns an initialized in-memory PIN store
+func NewMemoryPINStore() *MemoryPINStore {
+	return &MemoryPINStore{records: make(map[string]PINRecord)}
+}
+
+// GetPIN implements the PINStore interface
+func (s *MemoryPINStore) GetPIN(ctx context.Context, userID string) (*PINRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// SavePIN implements the PINStore interface
+func (s *MemoryPINStore) SavePIN(ctx context.Context, userID string, record PINRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[userID] = record
+	return nil
+}
+
+// AttemptPIN implements the PINStore interface
+func (s *MemoryPINStore) AttemptPIN(ctx context.Context, userID string, maxAttempts int, lockout time.Duration) (*PINRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[userID]
+	if !ok {
+		return nil, nil
+	}
+	before := record
+
+	now := time.Now()
+	if now.Before(record.LockedUntil) {
+		return &before, nil
+	}
+	record.FailedAttempts++
+	if record.FailedAttempts >= maxAttempts {
+		record.FailedAttempts = 0
+		record.LockedUntil = now.Add(lockout)
+	}
+	s.records[userID] = record
+	return &before, nil
+}
+
+// ResetPINAttempts implements the PINStore interface
+func (s *MemoryPINStore) ResetPINAttempts(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[userID]
+	if !ok {
+		return nil
+	}
+	record.FailedAttempts = 0
+	record.LockedUntil = time.Time{}
+	s.records[userID] = record
+	return nil
+}
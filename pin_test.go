@@ -0,0 +1,123 @@
+package serverutils_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePINStrength(t *testing.T) {
+	tests := []struct {
+		pin     string
+		wantErr bool
+	}{
+		{pin: "4829", wantErr: false},
+		{pin: "903215", wantErr: false},
+		{pin: "123", wantErr: true},
+		{pin: "123456789", wantErr: true},
+		{pin: "12a4", wantErr: true},
+		{pin: "0000", wantErr: true},
+		{pin: "1234", wantErr: true},
+		{pin: "6543", wantErr: true},
+		{pin: "2580", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pin, func(t *testing.T) {
+			err := serverutils.ValidatePINStrength(tt.pin)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestHashPIN(t *testing.T) {
+	hash, err := serverutils.HashPIN("4829", 4)
+	assert.Nil(t, err)
+	assert.True(t, serverutils.ComparePINHash(hash, "4829"))
+	assert.False(t, serverutils.ComparePINHash(hash, "4828"))
+
+	// the same PIN hashes differently every time
+	other, err := serverutils.HashPIN("4829", 4)
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, other)
+
+	assert.False(t, serverutils.ComparePINHash("not a hash", "4829"))
+	_, err = serverutils.HashPIN("4829", 0)
+	assert.NotNil(t, err)
+	_, err = serverutils.HashPIN("4829", serverutils.MaxPINHashCost+1)
+	assert.NotNil(t, err)
+
+	// stored hashes with an excessive cost are not computed
+	expensive := strings.Replace(hash, "$04$", "$31$", 1)
+	assert.False(t, serverutils.ComparePINHash(expensive, "4829"))
+}
+
+func TestPINService(t *testing.T) {
+	ctx := context.Background()
+	const userID = "user-1"
+
+	otp := serverutils.NewOTPService(serverutils.NewMemoryOTPStore())
+	service := serverutils.NewPINService(serverutils.NewMemoryPINStore(), otp).WithLimits(2, time.Minute, 4)
+
+	assert.True(t, errors.Is(service.VerifyPIN(ctx, userID, "4829"), serverutils.ErrPINNotSet))
+	assert.NotNil(t, service.SetPIN(ctx, userID, "1111"))
+	assert.Nil(t, service.SetPIN(ctx, userID, "4829"))
+	assert.NotNil(t, service.SetPIN(ctx, userID, "5831"))
+
+	assert.Nil(t, service.VerifyPIN(ctx, userID, "4829"))
+
+	assert.True(t, errors.Is(service.ChangePIN(ctx, userID, "5831", "7302"), serverutils.ErrInvalidPIN))
+	assert.Nil(t, service.ChangePIN(ctx, userID, "4829", "7302"))
+	assert.True(t, errors.Is(service.VerifyPIN(ctx, userID, "4829"), serverutils.ErrInvalidPIN))
+
+	// the second failure locks the PIN, even the right PIN is rejected
+	assert.True(t, errors.Is(service.VerifyPIN(ctx, userID, "4829"), serverutils.ErrPINLocked))
+	assert.True(t, errors.Is(service.VerifyPIN(ctx, userID, "7302"), serverutils.ErrPINLocked))
+
+	// resetting requires an OTP and lifts the lockout
+	assert.True(t, errors.Is(service.ResetPIN(ctx, userID, "000000", "6150"), serverutils.ErrInvalidOTP))
+	code, err := service.RequestPINReset(ctx, userID)
+	assert.Nil(t, err)
+
+	// the OTP only resets the PIN of the user it was issued for
+	assert.True(t, errors.Is(service.ResetPIN(ctx, "user-2", code, "6150"), serverutils.ErrInvalidOTP))
+
+	assert.Nil(t, service.ResetPIN(ctx, userID, code, "6150"))
+	assert.Nil(t, service.VerifyPIN(ctx, userID, "6150"))
+}
+
+func TestPINService_ParallelGuesses(t *testing.T) {
+	ctx := context.Background()
+	const userID = "user-1"
+
+	otp := serverutils.NewOTPService(serverutils.NewMemoryOTPStore())
+	service := serverutils.NewPINService(serverutils.NewMemoryPINStore(), otp).WithLimits(3, time.Minute, 4)
+	assert.Nil(t, service.SetPIN(ctx, userID, "4829"))
+
+	results := make(chan error, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- service.VerifyPIN(ctx, userID, "5831")
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	// only the attempts allowed before the lockout are checked against the PIN
+	invalid := 0
+	for err := range results {
+		if errors.Is(err, serverutils.ErrInvalidPIN) {
+			invalid++
+		}
+	}
+	assert.Equal(t, 2, invalid)
+	assert.True(t, errors.Is(service.VerifyPIN(ctx, userID, "4829"), serverutils.ErrPINLocked))
+}
@@ -1,7 +1,6 @@
 package serverutils
 
 import (
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -120,7 +119,7 @@ func (m *ImportPlugin) InjectSourceLate(schema *ast.Schema) *ast.Source {
 func (m *ImportPlugin) CreateSourceDirectory(path string) string {
 	dir, err := os.Getwd()
 	if err != nil {
-		log.Println(err)
+		Logger().Println(err)
 	}
 
 	dir = filepath.Join(dir, path, "imported")
@@ -129,14 +128,14 @@ func (m *ImportPlugin) CreateSourceDirectory(path string) string {
 	if _, err := os.Stat(dir); !os.IsNotExist(err) {
 		err = os.RemoveAll(dir)
 		if err != nil {
-			log.Println(err)
+			Logger().Println(err)
 		}
 	}
 
 	// create a new generated folder
 	err = os.Mkdir(dir, 0750)
 	if err != nil {
-		log.Println(err)
+		Logger().Println(err)
 	}
 
 	return dir
@@ -150,19 +149,19 @@ func (m *ImportPlugin) GenerateSchemaFile(dir string, source *ast.Source) {
 
 	f, err := os.Create(file)
 	if err != nil {
-		log.Println(err)
+		Logger().Println(err)
 	}
 
 	defer func() {
 		err := f.Close()
 		if err != nil {
-			log.Println(err)
+			Logger().Println(err)
 		}
 	}()
 
 	_, err = f.WriteString(source.Input)
 	if err != nil {
-		log.Println(err)
+		Logger().Println(err)
 
 	}
 }
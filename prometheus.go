@@ -0,0 +1,114 @@
+package serverutils
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"go.opencensus.io/stats/view"
+)
+
+// PrometheusContentType is the content type of the Prometheus text exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// characters that are not allowed in Prometheus metric and label names
+var invalidPrometheusNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// MetricsHandler serves the data of the supplied views, or of the DefaultServiceViews when
+// none are supplied, in the Prometheus text format e.g at /metrics.
+//
+// Only registered views (see view.Register) are served. Count views are counters,
+// distribution views are histograms and sum and last value views are gauges.
+func MetricsHandler(views ...*view.View) http.Handler {
+	if len(views) == 0 {
+		views = DefaultServiceViews
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		for _, v := range views {
+			rows, err := view.RetrieveData(v.Name)
+			if err != nil {
+				// the view is not registered
+				continue
+			}
+			writePrometheusView(&buf, v, rows)
+		}
+
+		w.Header().Set("Content-Type", PrometheusContentType)
+		_, err := w.Write(buf.Bytes())
+		if err != nil {
+			Logger().WithFields(log.Fields{"error": err}).Error("Unable to write the metrics")
+		}
+	})
+}
+
+func writePrometheusView(buf *bytes.Buffer, v *view.View, rows []*view.Row) {
+	name := prometheusName(v.Name)
+	metricType := "gauge"
+	switch v.Aggregation.Type {
+	case view.AggTypeCount:
+		metricType = "counter"
+	case view.AggTypeDistribution:
+		metricType = "histogram"
+	}
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, strings.ReplaceAll(v.Description, "\n", " "))
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, metricType)
+
+	// rows are sorted so that the output is stable
+	sort.Slice(rows, func(i, j int) bool {
+		return prometheusLabels(rows[i], "") < prometheusLabels(rows[j], "")
+	})
+	for _, row := range rows {
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			fmt.Fprintf(buf, "%s%s %d\n", name, prometheusLabels(row, ""), data.Value)
+		case *view.SumData:
+			fmt.Fprintf(buf, "%s%s %s\n", name, prometheusLabels(row, ""), formatPrometheusValue(data.Value))
+		case *view.LastValueData:
+			fmt.Fprintf(buf, "%s%s %s\n", name, prometheusLabels(row, ""), formatPrometheusValue(data.Value))
+		case *view.DistributionData:
+			// Prometheus buckets are cumulative
+			var cumulative int64
+			for i, bound := range v.Aggregation.Buckets {
+				if i < len(data.CountPerBucket) {
+					cumulative += data.CountPerBucket[i]
+				}
+				le := fmt.Sprintf(`le="%s"`, formatPrometheusValue(bound))
+				fmt.Fprintf(buf, "%s_bucket%s %d\n", name, prometheusLabels(row, le), cumulative)
+			}
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", name, prometheusLabels(row, `le="+Inf"`), data.Count)
+			fmt.Fprintf(buf, "%s_sum%s %s\n", name, prometheusLabels(row, ""), formatPrometheusValue(data.Sum()))
+			fmt.Fprintf(buf, "%s_count%s %d\n", name, prometheusLabels(row, ""), data.Count)
+		}
+	}
+}
+
+// prometheusLabels formats the tags of a row, followed by an extra label if any, as
+// Prometheus labels e.g {auth_flow="login",auth_status="OK"}
+func prometheusLabels(row *view.Row, extra string) string {
+	labels := []string{}
+	for _, t := range row.Tags {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(t.Value)
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, prometheusName(t.Key.Name()), value))
+	}
+	if extra != "" {
+		labels = append(labels, extra)
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func prometheusName(name string) string {
+	return invalidPrometheusNameChars.ReplaceAllString(name, "_")
+}
+
+func formatPrometheusValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
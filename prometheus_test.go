@@ -0,0 +1,43 @@
+package serverutils_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	views := []*view.View{serverutils.AuthFlowCountView, serverutils.AuthFlowLatencyView}
+	view.Unregister(views...)
+	err := view.Register(views...)
+	assert.Nil(t, err)
+	defer view.Unregister(views...)
+
+	serverutils.RecordAuthFlowMetrics(context.Background(), time.Now(), serverutils.AuthFlowLogin, nil)
+	serverutils.RecordAuthFlowMetrics(context.Background(), time.Now(), serverutils.AuthFlowRefresh, fmt.Errorf("invalid refresh token"))
+
+	rec := httptest.NewRecorder()
+	serverutils.MetricsHandler(views...).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, serverutils.PrometheusContentType, rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE auth_flow_count counter\n")
+	assert.Contains(t, body, `auth_flow_count{auth_flow="login",auth_status="OK"} 1`)
+	assert.Contains(t, body, `auth_flow_count{auth_flow="refresh",auth_status="FAILED"} 1`)
+	assert.Contains(t, body, "# TYPE auth_flow_latency_distribution histogram\n")
+	assert.Contains(t, body, `auth_flow_latency_distribution_bucket{auth_flow="login",auth_status="OK",le="+Inf"} 1`)
+	assert.Contains(t, body, `auth_flow_latency_distribution_count{auth_flow="login",auth_status="OK"} 1`)
+
+	// unregistered views are left out
+	rec = httptest.NewRecorder()
+	serverutils.MetricsHandler(serverutils.CircuitBreakerStateView).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Empty(t, rec.Body.String())
+}
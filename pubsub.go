@@ -0,0 +1,55 @@
+package serverutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MaxPubSubPushBytes is the size limit of Pub/Sub push request bodies.
+// Messages can be up to 10MB and their data is base64 encoded in push requests.
+const MaxPubSubPushBytes = 16 << 20
+
+// PubSubMessage is a message delivered by a Pub/Sub push subscription.
+// Data is the decoded message data.
+type PubSubMessage struct {
+	Data        []byte            `json:"data"`
+	Attributes  map[string]string `json:"attributes"`
+	MessageID   string            `json:"messageId"`
+	PublishTime time.Time         `json:"publishTime"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+// PubSubPayload is the body of a Pub/Sub push request
+type PubSubPayload struct {
+	Message      PubSubMessage `json:"message"`
+	Subscription string        `json:"subscription"`
+}
+
+// VerifyPubSubJWTAndDecodeMessage verifies the bearer token of a Pub/Sub push request and
+// returns the pushed message.
+//
+// For push subscriptions that are configured to authenticate, the verifier should be a
+// GoogleIDTokenVerifier whose Audience is the audience configured on the subscription and whose
+// AllowedServiceAccounts contain the subscription's service account.
+func VerifyPubSubJWTAndDecodeMessage(r *http.Request, verifier InterserviceTokenVerifier) (*PubSubPayload, error) {
+	token, err := BearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	_, err = verifier.Verify(r.Context(), token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Pub/Sub push token: %w", err)
+	}
+
+	payload := &PubSubPayload{}
+	err = json.NewDecoder(http.MaxBytesReader(nil, r.Body, MaxPubSubPushBytes)).Decode(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the Pub/Sub push payload: %w", err)
+	}
+	if payload.Message.MessageID == "" {
+		return nil, fmt.Errorf("the Pub/Sub push payload does not contain a message")
+	}
+	return payload, nil
+}
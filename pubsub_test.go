@@ -0,0 +1,67 @@
+package serverutils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPubSubJWTAndDecodeMessage(t *testing.T) {
+	const audience = "https://engagement.example.com/pubsub"
+	secret := []byte("secret")
+	verifier := &serverutils.HMACTokenVerifier{Secret: secret, Audience: audience, AllowedIssuers: []string{"pubsub"}}
+
+	token := func(issuer string) string {
+		source := &serverutils.HMACTokenSource{Issuer: issuer, Secret: secret}
+		token, err := source.Token(context.Background(), audience)
+		assert.Nil(t, err)
+		return token
+	}
+
+	validBody := `{
+		"message": {
+			"data": "aGVsbG8=",
+			"attributes": {"topic": "nudges"},
+			"messageId": "136969346945",
+			"publishTime": "2021-02-26T19:13:55.749Z"
+		},
+		"subscription": "projects/myproject/subscriptions/mysubscription"
+	}`
+
+	tests := []struct {
+		name    string
+		token   string
+		body    string
+		wantErr bool
+	}{
+		{name: "valid push", token: token("pubsub"), body: validBody},
+		{name: "no token", body: validBody, wantErr: true},
+		{name: "token from another issuer", token: token("someone-else"), body: validBody, wantErr: true},
+		{name: "invalid body", token: token("pubsub"), body: `{"message":`, wantErr: true},
+		{name: "no message", token: token("pubsub"), body: `{"subscription": "sub"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/pubsub", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			payload, err := serverutils.VerifyPubSubJWTAndDecodeMessage(req, verifier)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("hello"), payload.Message.Data)
+			assert.Equal(t, "nudges", payload.Message.Attributes["topic"])
+			assert.Equal(t, "136969346945", payload.Message.MessageID)
+			assert.Equal(t, "projects/myproject/subscriptions/mysubscription", payload.Subscription)
+		})
+	}
+}
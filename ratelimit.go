@@ -0,0 +1,216 @@
+package serverutils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RateLimit is a token bucket configuration.
+//
+// A bucket holds up to Burst tokens and is refilled with Requests tokens every Per.
+// Every request takes one token; requests are rejected when the bucket is empty.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// refillRate returns the number of tokens added to a bucket per second
+func (l RateLimit) refillRate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+func (l RateLimit) validate() error {
+	if l.Requests <= 0 || l.Per <= 0 || l.Burst <= 0 {
+		return fmt.Errorf("invalid rate limit %+v", l)
+	}
+	return nil
+}
+
+// RateLimitStore keeps the token buckets used by the rate limiting middleware.
+//
+// Allow takes a token from the bucket identified by key. When the bucket is empty it
+// returns false and how long the caller should wait before retrying.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error)
+}
+
+// RateLimitKeyFunc derives the key that identifies the caller of a request
+type RateLimitKeyFunc func(r *http.Request) string
+
+// ClientIP returns the IP address of the client of a service behind a single proxy e.g
+// Cloud Run (see ClientIPBehindProxies)
+func ClientIP(r *http.Request) string {
+	return ClientIPBehindProxies(r, 1)
+}
+
+// ClientIPBehindProxies returns the IP address of the client that made the request to a
+// service behind the supplied number of trusted proxies e.g 2 for a load balancer in front
+// of Cloud Run.
+//
+// Every proxy appends the address it received the request from to X-Forwarded-For, so the
+// entry that many places from the right is the one added by the outermost trusted proxy.
+// Entries further left are supplied by the client and can be spoofed. The address of the
+// connection is used when proxies is zero or the header is missing.
+func ClientIPBehindProxies(r *http.Request, proxies int) string {
+	forwarded := []string{}
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, splitCSV(value)...)
+	}
+	if proxies > 0 && len(forwarded) > 0 {
+		i := len(forwarded) - proxies
+		if i < 0 {
+			i = 0
+		}
+		return forwarded[i]
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// IPRateLimitKey rate limits requests per client IP address of a service behind a single
+// proxy (see IPRateLimitKeyBehindProxies)
+func IPRateLimitKey(r *http.Request) string {
+	return fmt.Sprintf("ip:%s", ClientIP(r))
+}
+
+// IPRateLimitKeyBehindProxies rate limits requests per client IP address of a service behind
+// the supplied number of trusted proxies
+func IPRateLimitKeyBehindProxies(proxies int) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return fmt.Sprintf("ip:%s", ClientIPBehindProxies(r, proxies))
+	}
+}
+
+// UIDOrIPRateLimitKey rate limits authenticated requests per user and anonymous requests
+// per client IP address.
+//
+// uid should return the UID of the authenticated user of the request, or an empty string
+// when the request is not authenticated.
+func UIDOrIPRateLimitKey(uid func(r *http.Request) string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		if id := uid(r); id != "" {
+			return fmt.Sprintf("uid:%s", id)
+		}
+		return IPRateLimitKey(r)
+	}
+}
+
+// RateLimitMiddleware rejects requests with a 429 status once the caller identified by
+// keyFunc exceeds the supplied limit.
+//
+// If the store fails, the request is let through so that a store outage does not take
+// down the service. It panics if the limit is invalid so that a misconfigured limit is
+// caught at startup instead of disabling rate limiting.
+func RateLimitMiddleware(store RateLimitStore, limit RateLimit, keyFunc RateLimitKeyFunc) func(http.Handler) http.Handler {
+	if err := limit.validate(); err != nil {
+		panic(err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				allowed, retryAfter, err := store.Allow(r.Context(), keyFunc(r), limit)
+				if err != nil {
+					Logger().WithFields(log.Fields{"error": err}).Error("Unable to check the rate limit")
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				if !allowed {
+					seconds := int(math.Ceil(retryAfter.Seconds()))
+					w.Header().Set("Retry-After", strconv.Itoa(seconds))
+					WriteJSONResponse(w, ErrorMap(fmt.Errorf("too many requests, retry in %d seconds", seconds)), http.StatusTooManyRequests)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	rate    float64
+	burst   int
+}
+
+// MemoryRateLimitStore is an in-memory RateLimitStore.
+//
+// It is suitable for a single instance of a service; buckets are not shared across instances.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	calls   int
+}
+
+// NewMemoryRateLimitStore returns an initialized in-memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// number of calls to Allow between sweeps of full buckets
+const rateLimitSweepInterval = 1000
+
+// Allow implements the RateLimitStore interface
+func (s *MemoryRateLimitStore) Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	if err := limit.validate(); err != nil {
+		return false, 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	rate := limit.refillRate()
+
+	s.calls++
+	if s.calls%rateLimitSweepInterval == 0 {
+		s.sweep(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = bucket
+	}
+	bucket.rate, bucket.burst = rate, limit.Burst
+
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed*rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+		return false, wait, nil
+	}
+
+	bucket.tokens--
+	return true, 0, nil
+}
+
+// sweep removes the buckets that have since been refilled at their own rate; they are
+// equivalent to new buckets
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*bucket.rate >= float64(bucket.burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package serverutils_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		proxies    int
+		want       string
+	}{
+		{
+			name:       "remote address",
+			remoteAddr: "10.0.0.1:5000",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "remote address without port",
+			remoteAddr: "10.0.0.1",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "10.0.0.1:5000",
+			forwarded:  []string{"1.1.1.1, 2.2.2.2"},
+			proxies:    1,
+			want:       "2.2.2.2",
+		},
+		{
+			name:       "behind two proxies",
+			remoteAddr: "10.0.0.1:5000",
+			forwarded:  []string{"6.6.6.6, 1.1.1.1, 2.2.2.2"},
+			proxies:    2,
+			want:       "1.1.1.1",
+		},
+		{
+			name:       "several headers",
+			remoteAddr: "10.0.0.1:5000",
+			forwarded:  []string{"6.6.6.6", "1.1.1.1, 2.2.2.2"},
+			proxies:    2,
+			want:       "1.1.1.1",
+		},
+		{
+			name:       "fewer entries than proxies",
+			remoteAddr: "10.0.0.1:5000",
+			forwarded:  []string{"1.1.1.1"},
+			proxies:    2,
+			want:       "1.1.1.1",
+		},
+		{
+			name:       "no trusted proxies",
+			remoteAddr: "10.0.0.1:5000",
+			forwarded:  []string{"6.6.6.6"},
+			proxies:    0,
+			want:       "10.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			assert.Equal(t, tt.want, serverutils.ClientIPBehindProxies(req, tt.proxies))
+			if tt.proxies == 1 {
+				assert.Equal(t, tt.want, serverutils.ClientIP(req))
+			}
+		})
+	}
+}
+
+func TestUIDOrIPRateLimitKey(t *testing.T) {
+	keyFunc := serverutils.UIDOrIPRateLimitKey(func(r *http.Request) string {
+		return r.Header.Get("X-UID")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	assert.Equal(t, "ip:10.0.0.1", keyFunc(req))
+
+	req.Header.Set("X-UID", "a-user")
+	assert.Equal(t, "uid:a-user", keyFunc(req))
+}
+
+func TestMemoryRateLimitStore_Allow(t *testing.T) {
+	ctx := context.Background()
+	store := serverutils.NewMemoryRateLimitStore()
+	limit := serverutils.RateLimit{Requests: 1000, Per: time.Second, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := store.Allow(ctx, "key", limit)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, retryAfter, err := store.Allow(ctx, "key", limit)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+	assert.True(t, retryAfter > 0)
+
+	// other callers have their own buckets
+	allowed, _, err = store.Allow(ctx, "another-key", limit)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// the bucket is refilled over time
+	time.Sleep(5 * time.Millisecond)
+	allowed, _, err = store.Allow(ctx, "key", limit)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	_, _, err = store.Allow(ctx, "key", serverutils.RateLimit{})
+	assert.NotNil(t, err)
+}
+
+func TestMemoryRateLimitStore_SweepUsesBucketRate(t *testing.T) {
+	ctx := context.Background()
+	store := serverutils.NewMemoryRateLimitStore()
+	slow := serverutils.RateLimit{Requests: 1, Per: time.Hour, Burst: 1}
+	fast := serverutils.RateLimit{Requests: 1000000, Per: time.Second, Burst: 1}
+
+	allowed, _, err := store.Allow(ctx, "slow", slow)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// enough calls with another limit to sweep the buckets
+	for i := 0; i < 1000; i++ {
+		_, _, err := store.Allow(ctx, "fast", fast)
+		assert.Nil(t, err)
+	}
+
+	// the slow bucket has not refilled at its own rate, so it is kept
+	allowed, _, err = store.Allow(ctx, "slow", slow)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	limit := serverutils.RateLimit{Requests: 1, Per: time.Hour, Burst: 1}
+	h := serverutils.RateLimitMiddleware(serverutils.NewMemoryRateLimitStore(), limit, serverutils.IPRateLimitKey)(next)
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/login", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/login", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "3600", rw.Header().Get("Retry-After"))
+
+	// store failures let requests through
+	failing := serverutils.RateLimitMiddleware(failingRateLimitStore{}, limit, serverutils.IPRateLimitKey)(next)
+	rw = httptest.NewRecorder()
+	failing.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/login", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	// invalid limits are rejected instead of disabling rate limiting
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	serverutils.RateLimitMiddleware(serverutils.NewMemoryRateLimitStore(), serverutils.RateLimit{}, serverutils.IPRateLimitKey)
+	t.Error("an invalid limit should panic")
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Allow(ctx context.Context, key string, limit serverutils.RateLimit) (bool, time.Duration, error) {
+	return false, 0, fmt.Errorf("the store is unavailable")
+}
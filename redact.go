@@ -0,0 +1,56 @@
+package serverutils
+
+import (
+	"net/http"
+	"regexp"
+)
+
+// RedactedValue replaces the sensitive values removed by RedactPII
+const RedactedValue = "[REDACTED]"
+
+var (
+	emailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phoneNumberPattern = regexp.MustCompile(`\+\d{8,15}\b|\b0\d{9}\b`)
+)
+
+// the order matters: credentials are masked before the more general patterns run
+var piiPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Bearer <token> and Authorization: Basic <credentials>
+	{regexp.MustCompile(`(?i)(\bbearer\s+|authorization:\s*basic\s+)[A-Za-z0-9\-._~+/]+=*`), "${1}" + RedactedValue},
+	// "password": "...", password=..., pin: ... in JSON, form bodies and query strings
+	{
+		regexp.MustCompile(`(?i)("?\b(?:password|passwd|pin|otp|secret|token|access_?token|refresh_?token|api_?key|apikey)"?\s*[:=]\s*"?)[^"&,\s}]+`),
+		"${1}" + RedactedValue,
+	},
+	// email addresses
+	{emailPattern, RedactedValue},
+	// phone numbers in the international (+254711223344) or national (0711223344) format
+	{phoneNumberPattern, RedactedValue},
+}
+
+// sensitiveHeaders are masked by RedactHeaders
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Apikey"}
+
+// RedactPII masks bearer tokens, passwords and similar credentials, email addresses and phone
+// numbers in the supplied text so that it can be logged
+func RedactPII(s string) string {
+	for _, p := range piiPatterns {
+		s = p.pattern.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// RedactHeaders returns a copy of the supplied headers with the values of credential
+// headers e.g Authorization and Cookie masked
+func RedactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if _, ok := redacted[http.CanonicalHeaderKey(name)]; ok {
+			redacted.Set(name, RedactedValue)
+		}
+	}
+	return redacted
+}
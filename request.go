@@ -0,0 +1,150 @@
+package serverutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes is the request body size limit used when no limit is supplied
+const DefaultMaxBodyBytes = 1 << 20 // 1 MiB
+
+// RequestBodyError is returned when a request body can't be decoded.
+//
+// StatusCode is the HTTP status that should be sent back to the client:
+// 400 for malformed bodies, 413 for bodies that are too large and 415
+// for bodies that are not JSON.
+type RequestBodyError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RequestBodyError) Error() string {
+	return e.Message
+}
+
+// DecodeJSONBody decodes the JSON body of the request into dst.
+//
+// Bodies larger than maxBytes are rejected; when maxBytes is not positive
+// DefaultMaxBodyBytes is used. Any error returned is a *RequestBodyError.
+func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	if err := decodeJSONBody(w, r, dst, maxBytes, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DecodeStrictJSONBody is like DecodeJSONBody but also rejects bodies that
+// contain fields that are not present in dst
+func DecodeStrictJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	if err := decodeJSONBody(w, r, dst, maxBytes, true); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DecodeJSONRequest decodes the JSON body of the request into dst like DecodeJSONBody.
+//
+// When the body can't be decoded, the error is written to the response with the status of the
+// RequestBodyError and returned so that the caller can stop handling the request.
+func DecodeJSONRequest(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	if err := decodeJSONBody(w, r, dst, maxBytes, false); err != nil {
+		WriteJSONResponse(w, ErrorMap(err), err.StatusCode)
+		return err
+	}
+	return nil
+}
+
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64, disallowUnknownFields bool) *RequestBodyError {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return &RequestBodyError{
+				StatusCode: http.StatusUnsupportedMediaType,
+				Message:    "Content-Type header is not application/json",
+			}
+		}
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	if disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	err := decoder.Decode(dst)
+	if err != nil {
+		return requestBodyError(err, maxBytes)
+	}
+
+	// the body should contain a single JSON value
+	err = decoder.Decode(&struct{}{})
+	if !errors.Is(err, io.EOF) {
+		return &RequestBodyError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "request body must only contain a single JSON value",
+		}
+	}
+
+	return nil
+}
+
+func requestBodyError(err error, maxBytes int64) *RequestBodyError {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var maxBytesError *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		return &RequestBodyError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("request body contains badly-formed JSON (at position %d)", syntaxError.Offset),
+		}
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &RequestBodyError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "request body contains badly-formed JSON",
+		}
+
+	case errors.As(err, &typeError):
+		return &RequestBodyError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("request body contains an invalid value for the %q field", typeError.Field),
+		}
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &RequestBodyError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("request body contains unknown field %s", field),
+		}
+
+	case errors.Is(err, io.EOF):
+		return &RequestBodyError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "request body must not be empty",
+		}
+
+	case errors.As(err, &maxBytesError):
+		return &RequestBodyError{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Message:    fmt.Sprintf("request body must not be larger than %d bytes", maxBytes),
+		}
+
+	default:
+		return &RequestBodyError{
+			StatusCode: http.StatusBadRequest,
+			Message:    err.Error(),
+		}
+	}
+}
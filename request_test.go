@@ -0,0 +1,133 @@
+package serverutils_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeJSONBody(t *testing.T) {
+	type target struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		maxBytes    int64
+		strict      bool
+		wantStatus  int
+	}{
+		{
+			name:        "valid body",
+			body:        `{"name": "test", "age": 10}`,
+			contentType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "valid body without content type",
+			body:     `{"name": "test"}`,
+			maxBytes: -1,
+		},
+		{
+			name:        "unknown fields are allowed",
+			body:        `{"name": "test", "other": true}`,
+			contentType: "application/json",
+		},
+		{
+			name:        "unknown fields rejected in strict mode",
+			body:        `{"name": "test", "other": true}`,
+			contentType: "application/json",
+			strict:      true,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "wrong content type",
+			body:        `{"name": "test"}`,
+			contentType: "text/plain",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "too large",
+			body:       `{"name": "a very long name"}`,
+			maxBytes:   10,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "badly formed",
+			body:       `{"name": "test",}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "truncated",
+			body:       `{"name": "test"`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "wrong type",
+			body:       `{"age": "ten"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty",
+			body:       ``,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "several values",
+			body:       `{"name": "a"}{"name": "b"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			dst := target{}
+			var err error
+			if tt.strict {
+				err = serverutils.DecodeStrictJSONBody(w, r, &dst, tt.maxBytes)
+			} else {
+				err = serverutils.DecodeJSONBody(w, r, &dst, tt.maxBytes)
+			}
+
+			if tt.wantStatus == 0 {
+				assert.Nil(t, err)
+				assert.Equal(t, "test", dst.Name)
+				return
+			}
+
+			var bodyErr *serverutils.RequestBodyError
+			assert.True(t, errors.As(err, &bodyErr))
+			assert.Equal(t, tt.wantStatus, bodyErr.StatusCode)
+			assert.NotEmpty(t, bodyErr.Error())
+		})
+	}
+}
+
+func TestDecodeJSONRequest(t *testing.T) {
+	dst := map[string]string{}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "test"}`))
+	assert.Nil(t, serverutils.DecodeJSONRequest(w, r, &dst, 0))
+	assert.Equal(t, "test", dst["name"])
+	assert.Equal(t, 0, w.Body.Len())
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "test"}`))
+	r.Header.Set("Content-Type", "text/plain")
+	assert.NotNil(t, serverutils.DecodeJSONRequest(w, r, &dst, 0))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), "error")
+}
@@ -0,0 +1,95 @@
+package serverutils
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ResponseEnvelope is the standard shape of JSON responses.
+//
+// Successful responses carry data and optionally meta e.g pagination details.
+// Failed responses carry an error.
+type ResponseEnvelope struct {
+	Data  interface{}            `json:"data,omitempty"`
+	Error *ResponseError         `json:"error,omitempty"`
+	Meta  map[string]interface{} `json:"meta,omitempty"`
+}
+
+// ResponseError is the error in a response envelope
+type ResponseError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+// WriteEnvelopeResponse writes the supplied data and meta in a response envelope
+func WriteEnvelopeResponse(w http.ResponseWriter, r *http.Request, data interface{}, meta map[string]interface{}, status int) {
+	WriteNegotiatedJSONResponse(w, r, ResponseEnvelope{Data: data, Meta: meta}, status)
+}
+
+// WriteErrorEnvelopeResponse writes the supplied error in a response envelope
+func WriteErrorEnvelopeResponse(w http.ResponseWriter, r *http.Request, err error, status int) {
+	WriteNegotiatedJSONResponse(w, r, ResponseEnvelope{
+		Error: &ResponseError{Message: err.Error(), Code: status},
+	}, status)
+}
+
+// WriteNegotiatedJSONResponse writes the content supplied via the `source` parameter as JSON
+// like WriteJSONResponse, compressing it with gzip when the request accepts it.
+//
+// Every response varies by Accept-Encoding so that caches don't serve the gzipped body to
+// clients that don't accept it.
+func WriteNegotiatedJSONResponse(w http.ResponseWriter, r *http.Request, source interface{}, status int) {
+	if !headerHasToken(w.Header(), "Vary", "Accept-Encoding") {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if !AcceptsGzip(r) {
+		WriteJSONResponse(w, source, status)
+		return
+	}
+
+	content, err := json.Marshal(source)
+	if err != nil {
+		msg := fmt.Sprintf("error when marshalling %#v to JSON bytes: %#v", source, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(status)
+
+	gz := gzip.NewWriter(w)
+	_, err = gz.Write(content)
+	if err != nil {
+		Logger().WithFields(log.Fields{"error": err}).Error("Unable to write gzipped JSON response")
+		return
+	}
+	err = gz.Close()
+	if err != nil {
+		Logger().WithFields(log.Fields{"error": err}).Error("Unable to write gzipped JSON response")
+	}
+}
+
+// AcceptsGzip reports whether the client that made the request accepts gzip encoded responses
+func AcceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(encoding, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		// a quality value of zero e.g `gzip;q=0` means gzip is not acceptable
+		params = strings.TrimSpace(params)
+		if !strings.HasPrefix(params, "q=") {
+			return true
+		}
+		quality, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+		return err != nil || quality > 0
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package serverutils_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "not set", header: "", want: false},
+		{name: "gzip", header: "gzip", want: true},
+		{name: "several encodings", header: "deflate, GZIP;q=0.8, br", want: true},
+		{name: "gzip refused", header: "br, gzip;q=0", want: false},
+		{name: "other encodings", header: "br, deflate", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", tt.header)
+			assert.Equal(t, tt.want, serverutils.AcceptsGzip(req))
+		})
+	}
+}
+
+func TestWriteEnvelopeResponse(t *testing.T) {
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	serverutils.WriteEnvelopeResponse(rw, req, map[string]string{"name": "test"}, map[string]interface{}{"count": 1}, http.StatusOK)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	assert.Equal(t, `{"data":{"name":"test"},"meta":{"count":1}}`, rw.Body.String())
+}
+
+func TestWriteErrorEnvelopeResponse(t *testing.T) {
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	serverutils.WriteErrorEnvelopeResponse(rw, req, fmt.Errorf("not found"), http.StatusNotFound)
+
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+	assert.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+
+	gz, err := gzip.NewReader(rw.Body)
+	assert.Nil(t, err)
+	content, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+
+	envelope := serverutils.ResponseEnvelope{}
+	assert.Nil(t, json.Unmarshal(content, &envelope))
+	assert.Nil(t, envelope.Data)
+	assert.Equal(t, "not found", envelope.Error.Message)
+	assert.Equal(t, http.StatusNotFound, envelope.Error.Code)
+}
+
+func TestWriteNegotiatedJSONResponse(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rw := httptest.NewRecorder()
+	serverutils.WriteNegotiatedJSONResponse(rw, req, make(chan string), http.StatusOK)
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+
+	errWriter := serverutils.NewErrorResponseWriter(fmt.Errorf("ka-boom"))
+	serverutils.WriteNegotiatedJSONResponse(errWriter, req, map[string]string{"a": "b"}, http.StatusOK)
+}
+
+func TestWriteNegotiatedJSONResponse_Vary(t *testing.T) {
+	for _, encoding := range []string{"gzip", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+
+		rw := httptest.NewRecorder()
+		rw.Header().Add("Vary", "Accept-Encoding")
+		serverutils.WriteNegotiatedJSONResponse(rw, req, map[string]string{"a": "b"}, http.StatusOK)
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, []string{"Accept-Encoding"}, rw.Header().Values("Vary"))
+
+		rw = httptest.NewRecorder()
+		serverutils.WriteNegotiatedJSONResponse(rw, req, map[string]string{"a": "b"}, http.StatusOK)
+		assert.Equal(t, "Accept-Encoding", rw.Header().Get("Vary"))
+	}
+}
@@ -0,0 +1,161 @@
+package serverutils
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// ContentCategory is the reason user generated content is flagged
+type ContentCategory string
+
+// content categories flagged by the default screener
+const (
+	ContentCategoryProfanity   ContentCategory = "PROFANITY"
+	ContentCategoryPhoneNumber ContentCategory = "PHONE_NUMBER"
+	ContentCategoryEmail       ContentCategory = "EMAIL"
+	ContentCategoryIDNumber    ContentCategory = "ID_NUMBER"
+)
+
+// ContentFlag is a part of the screened content that was flagged
+type ContentFlag struct {
+	Category ContentCategory `json:"category"`
+	Match    string          `json:"match"`
+}
+
+// ContentVerdict is the result of screening user generated content
+type ContentVerdict struct {
+	Flags []ContentFlag `json:"flags,omitempty"`
+}
+
+// Allowed reports whether nothing was flagged
+func (v ContentVerdict) Allowed() bool {
+	return len(v.Flags) == 0
+}
+
+// Flagged reports whether content of the supplied category was flagged
+func (v ContentVerdict) Flagged(category ContentCategory) bool {
+	for _, flag := range v.Flags {
+		if flag.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentScreener checks user generated content e.g messages before it is saved or shared.
+// Callers decide what to do with flagged content e.g reject it or hold it for moderation.
+type ContentScreener interface {
+	Screen(ctx context.Context, text string) (*ContentVerdict, error)
+}
+
+// defaultBlockedWords are flagged by the default screener
+var defaultBlockedWords = []string{
+	"asshole", "bastard", "bitch", "bullshit", "cunt", "dick", "fuck", "fucker", "fucking",
+	"motherfucker", "shit", "slut", "whore",
+}
+
+var (
+	// digits that may be grouped with spaces, dashes, dots or brackets e.g 0711 223 344;
+	// phone numbers are looked for within each candidate (see maskPhoneNumbers)
+	phoneCandidatePattern = regexp.MustCompile(`\+?\(?\b\d[\d \-.()]*\d\b`)
+
+	// a group of digits within a candidate e.g +254, (0711)
+	digitGroupPattern = regexp.MustCompile(`\+?\(?\d+\)?`)
+
+	// passport numbers e.g A1234567
+	passportNumberPattern = regexp.MustCompile(`\b[A-Za-z]\d{7,8}\b`)
+
+	// national ID numbers e.g 12345678 are only flagged after a mention of an ID, since
+	// amounts and other numbers have the same digits
+	idNumberPattern = regexp.MustCompile(`(?i)\b(?:id|identity|passport)(?:\s+(?:card|no|number))?\.?\s*(?:is|:|#)?\s*(\d{7,8})\b`)
+
+	wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+)
+
+// isPhoneNumber reports whether a candidate is a phone number in the international format
+// (+254 711 223 344), the national format (0711 223 344) or the international format
+// without a plus (254711223344). Grouped digits without a plus or a leading zero are not
+// flagged since they are more likely to be amounts or lists of numbers.
+func isPhoneNumber(candidate string) bool {
+	digits := stripPhoneSeparators(candidate)
+	grouped := digits != candidate
+	switch {
+	case strings.HasPrefix(digits, "+"):
+		return len(digits)-1 >= 8 && len(digits)-1 <= 15
+	case strings.HasPrefix(digits, "0"):
+		return len(digits) == 10
+	default:
+		return !grouped && len(digits) >= 11 && len(digits) <= 15
+	}
+}
+
+// maskPhoneNumbers replaces the phone numbers within a candidate with a space and returns
+// them. A candidate may hold other numbers too e.g `room 12 0711223344`, so the longest run
+// of digit groups that is a phone number is looked for from each group onwards.
+func maskPhoneNumbers(candidate string) (string, []string) {
+	groups := digitGroupPattern.FindAllStringIndex(candidate, -1)
+	var masked strings.Builder
+	var phoneNumbers []string
+	written := 0
+	for i := 0; i < len(groups); i++ {
+		for j := len(groups) - 1; j >= i; j-- {
+			start, end := groups[i][0], groups[j][1]
+			if !isPhoneNumber(candidate[start:end]) {
+				continue
+			}
+			phoneNumbers = append(phoneNumbers, candidate[start:end])
+			masked.WriteString(candidate[written:start])
+			masked.WriteString(" ")
+			written = end
+			i = j
+			break
+		}
+	}
+	masked.WriteString(candidate[written:])
+	return masked.String(), phoneNumbers
+}
+
+// WordListScreener is a ContentScreener that flags blocked words, phone numbers, email
+// addresses and ID numbers
+type WordListScreener struct {
+	blocked map[string]struct{}
+}
+
+// NewWordListScreener returns a screener that flags the default blocked words as well as
+// the supplied words e.g local language profanity
+func NewWordListScreener(blockedWords ...string) *WordListScreener {
+	s := &WordListScreener{blocked: make(map[string]struct{})}
+	for _, word := range append(defaultBlockedWords, blockedWords...) {
+		s.blocked[strings.ToLower(word)] = struct{}{}
+	}
+	return s
+}
+
+// Screen implements the ContentScreener interface
+func (s *WordListScreener) Screen(ctx context.Context, text string) (*ContentVerdict, error) {
+	verdict := &ContentVerdict{}
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		if _, ok := s.blocked[strings.ToLower(word)]; ok {
+			verdict.Flags = append(verdict.Flags, ContentFlag{Category: ContentCategoryProfanity, Match: word})
+		}
+	}
+	for _, match := range emailPattern.FindAllString(text, -1) {
+		verdict.Flags = append(verdict.Flags, ContentFlag{Category: ContentCategoryEmail, Match: match})
+	}
+	// phone numbers are removed so that their digits are not flagged as ID numbers too
+	text = phoneCandidatePattern.ReplaceAllStringFunc(text, func(candidate string) string {
+		masked, phoneNumbers := maskPhoneNumbers(candidate)
+		for _, match := range phoneNumbers {
+			verdict.Flags = append(verdict.Flags, ContentFlag{Category: ContentCategoryPhoneNumber, Match: match})
+		}
+		return masked
+	})
+	for _, match := range idNumberPattern.FindAllStringSubmatch(text, -1) {
+		verdict.Flags = append(verdict.Flags, ContentFlag{Category: ContentCategoryIDNumber, Match: match[1]})
+	}
+	for _, match := range passportNumberPattern.FindAllString(text, -1) {
+		verdict.Flags = append(verdict.Flags, ContentFlag{Category: ContentCategoryIDNumber, Match: match})
+	}
+	return verdict, nil
+}
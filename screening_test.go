@@ -0,0 +1,101 @@
+package serverutils_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordListScreener_Screen(t *testing.T) {
+	screener := serverutils.NewWordListScreener("mjinga")
+
+	tests := []struct {
+		name string
+		text string
+		want []serverutils.ContentFlag
+	}{
+		{name: "clean", text: "Habari, my appointment is at 10am on 2023-03-14"},
+		{
+			name: "profanity",
+			text: "This is SHIT, you mjinga",
+			want: []serverutils.ContentFlag{
+				{Category: serverutils.ContentCategoryProfanity, Match: "SHIT"},
+				{Category: serverutils.ContentCategoryProfanity, Match: "mjinga"},
+			},
+		},
+		{name: "blocked words inside other words are allowed", text: "Scunthorpe is a town", want: nil},
+		{
+			name: "phone numbers",
+			text: "call me on +254711223344 or 0711223344",
+			want: []serverutils.ContentFlag{
+				{Category: serverutils.ContentCategoryPhoneNumber, Match: "+254711223344"},
+				{Category: serverutils.ContentCategoryPhoneNumber, Match: "0711223344"},
+			},
+		},
+		{
+			name: "grouped phone numbers",
+			text: "call 0711 223 344, +254 711 223 344 or 254711223344",
+			want: []serverutils.ContentFlag{
+				{Category: serverutils.ContentCategoryPhoneNumber, Match: "0711 223 344"},
+				{Category: serverutils.ContentCategoryPhoneNumber, Match: "+254 711 223 344"},
+				{Category: serverutils.ContentCategoryPhoneNumber, Match: "254711223344"},
+			},
+		},
+		{
+			name: "dashes and brackets",
+			text: "or (0711) 223-344",
+			want: []serverutils.ContentFlag{
+				{Category: serverutils.ContentCategoryPhoneNumber, Match: "(0711) 223-344"},
+			},
+		},
+		{
+			name: "phone number after another number",
+			text: "room 12 0711223344",
+			want: []serverutils.ContentFlag{
+				{Category: serverutils.ContentCategoryPhoneNumber, Match: "0711223344"},
+			},
+		},
+		{
+			name: "grouped phone number after an amount",
+			text: "ksh 500 0711 223 344",
+			want: []serverutils.ContentFlag{
+				{Category: serverutils.ContentCategoryPhoneNumber, Match: "0711 223 344"},
+			},
+		},
+		{name: "amounts", text: "I paid 1500000 shillings for 12345678 units, 100 200 300 400"},
+		{
+			name: "passport number",
+			text: "my passport is A1234567",
+			want: []serverutils.ContentFlag{
+				{Category: serverutils.ContentCategoryIDNumber, Match: "A1234567"},
+			},
+		},
+		{
+			name: "email and ID number",
+			text: "my ID is 12345678, email jane@example.com",
+			want: []serverutils.ContentFlag{
+				{Category: serverutils.ContentCategoryEmail, Match: "jane@example.com"},
+				{Category: serverutils.ContentCategoryIDNumber, Match: "12345678"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := screener.Screen(context.Background(), tt.text)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, verdict.Flags)
+			assert.Equal(t, len(tt.want) == 0, verdict.Allowed())
+		})
+	}
+}
+
+func TestContentVerdict_Flagged(t *testing.T) {
+	verdict := serverutils.ContentVerdict{Flags: []serverutils.ContentFlag{
+		{Category: serverutils.ContentCategoryEmail, Match: "jane@example.com"},
+	}}
+	assert.True(t, verdict.Flagged(serverutils.ContentCategoryEmail))
+	assert.False(t, verdict.Flagged(serverutils.ContentCategoryProfanity))
+	assert.False(t, verdict.Allowed())
+}
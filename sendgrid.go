@@ -0,0 +1,142 @@
+package serverutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SendGridSendURL is the SendGrid v3 API endpoint that sends emails
+const SendGridSendURL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridMailer sends emails through the SendGrid v3 API
+type SendGridMailer struct {
+	apiKey     string
+	sendURL    string
+	httpClient *http.Client
+}
+
+// NewSendGridMailer returns a SendGrid mailer that authenticates with the supplied API key
+func NewSendGridMailer(apiKey string) *SendGridMailer {
+	return &SendGridMailer{
+		apiKey:  apiKey,
+		sendURL: SendGridSendURL,
+		httpClient: &http.Client{
+			Timeout:   InterserviceRequestTimeout,
+			Transport: NewTracingTransport(NewMetricsTransport(nil)),
+		},
+	}
+}
+
+// NewSendGridMailerFromEnv returns a SendGrid mailer that authenticates with the API key in
+// the SENDGRID_API_KEY environment variable
+func NewSendGridMailerFromEnv() (*SendGridMailer, error) {
+	apiKey, err := GetEnvVar(SendGridAPIKeyEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	return NewSendGridMailer(apiKey), nil
+}
+
+// WithSendURL points the mailer to another endpoint e.g a test server
+func (m *SendGridMailer) WithSendURL(url string) *SendGridMailer {
+	m.sendURL = url
+	return m
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type,omitempty"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+}
+
+// SendEmail implements the Mailer interface
+func (m *SendGridMailer) SendEmail(ctx context.Context, message EmailMessage) error {
+	err := validateEmailMessage(message)
+	if err != nil {
+		return err
+	}
+
+	payload := sendGridMessage{
+		From:    sendGridAddress{Email: message.From},
+		Subject: message.Subject,
+	}
+	personalization := sendGridPersonalization{}
+	for _, address := range message.To {
+		personalization.To = append(personalization.To, sendGridAddress{Email: address})
+	}
+	payload.Personalizations = []sendGridPersonalization{personalization}
+
+	// SendGrid requires the plain text content to come first
+	if message.Text != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/plain", Value: message.Text})
+	}
+	if message.HTML != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/html", Value: message.HTML})
+	}
+	for _, attachment := range message.Attachments {
+		payload.Attachments = append(payload.Attachments, sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(attachment.Content),
+			Type:        attachment.ContentType,
+			Filename:    attachment.Filename,
+			Disposition: "attachment",
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal the SendGrid payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.sendURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to compose the SendGrid request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return &EmailSendError{Temporary: true, Err: err}
+	}
+	defer closeBody(resp)
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		Logger().Println(err)
+	}
+	return &EmailSendError{
+		Temporary: resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError,
+		Err:       fmt.Errorf("SendGrid responded with status %d: %s", resp.StatusCode, string(content)),
+	}
+}
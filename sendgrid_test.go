@@ -0,0 +1,67 @@
+package serverutils_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendGridMailer_SendEmail(t *testing.T) {
+	var received map[string]interface{}
+	status := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer api-key", r.Header.Get("Authorization"))
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	mailer := serverutils.NewSendGridMailer("api-key").WithSendURL(srv.URL)
+	message := serverutils.EmailMessage{
+		From:    "noreply@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Your report",
+		Text:    "Hello",
+		HTML:    "<p>Hello</p>",
+		Attachments: []serverutils.EmailAttachment{
+			{Filename: "report.txt", ContentType: "text/plain", Content: []byte("report")},
+		},
+	}
+
+	err := mailer.SendEmail(context.Background(), message)
+	assert.Nil(t, err)
+	assert.Equal(t, "Your report", received["subject"])
+	assert.Len(t, received["content"], 2)
+	assert.Len(t, received["attachments"], 1)
+
+	status = http.StatusTooManyRequests
+	err = mailer.SendEmail(context.Background(), message)
+	var sendErr *serverutils.EmailSendError
+	assert.True(t, errors.As(err, &sendErr))
+	assert.True(t, sendErr.Temporary)
+
+	status = http.StatusBadRequest
+	err = mailer.SendEmail(context.Background(), message)
+	assert.True(t, errors.As(err, &sendErr))
+	assert.False(t, sendErr.Temporary)
+
+	message.To = []string{"not an address"}
+	assert.NotNil(t, mailer.SendEmail(context.Background(), message))
+}
+
+func TestNewSendGridMailerFromEnv(t *testing.T) {
+	setEnv(t, map[string]string{serverutils.SendGridAPIKeyEnvVarName: ""})
+	_, err := serverutils.NewSendGridMailerFromEnv()
+	assert.NotNil(t, err)
+
+	setEnv(t, map[string]string{serverutils.SendGridAPIKeyEnvVarName: "api-key"})
+	mailer, err := serverutils.NewSendGridMailerFromEnv()
+	assert.Nil(t, err)
+	assert.NotNil(t, mailer)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -11,7 +12,9 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"cloud.google.com/go/errorreporting"
@@ -73,23 +76,29 @@ func ErrorMap(err error) map[string]string {
 	return errMap
 }
 
-// RequestDebugMiddleware dumps the incoming HTTP request to the log for inspection
+// RequestDebugMiddleware dumps the incoming HTTP request to the log for inspection.
+// Credentials and PII e.g tokens, passwords, emails and phone numbers are redacted.
 func RequestDebugMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
-				body, err := io.ReadAll(r.Body)
-				if err != nil {
-					log.Errorf("Unable to read request body for debugging: error %#v", err)
-				}
 				if IsDebug() {
-					req, err := httputil.DumpRequest(r, true)
+					body, err := io.ReadAll(r.Body)
+					if err != nil {
+						Logger().Errorf("Unable to read request body for debugging: error %#v", err)
+					}
+					r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+					// dump a clone so that credentials in the headers are never logged
+					clone := r.Clone(r.Context())
+					clone.Header = RedactHeaders(r.Header)
+					clone.Body = io.NopCloser(bytes.NewReader(body))
+					req, err := httputil.DumpRequest(clone, true)
 					if err != nil {
-						log.Errorf("Unable to dump cloned request for debugging: error %#v", err)
+						Logger().Errorf("Unable to dump cloned request for debugging: error %#v", err)
 					}
-					log.Printf("Raw request: %v", string(req))
+					Logger().Printf("Raw request: %v", RedactPII(string(req)))
 				}
-				r.Body = io.NopCloser(bytes.NewBuffer(body))
 				next.ServeHTTP(w, r)
 			},
 		)
@@ -104,11 +113,12 @@ func LogStartupError(ctx context.Context, err error) {
 		if errorClient != nil {
 			errorClient.Report(errorreporting.Entry{Error: err})
 		}
-		log.WithFields(log.Fields{"error": err}).Error("Server startup error")
+		Logger().WithFields(log.Fields{"error": err}).Error("Server startup error")
 	}
 }
 
 // DecodeJSONToTargetStruct maps JSON from a HTTP request to a struct.
+// Use DecodeJSONRequest to limit the size and content type of the body.
 // TODO: Move to common helpers
 func DecodeJSONToTargetStruct(w http.ResponseWriter, r *http.Request, targetStruct interface{}) {
 	err := json.NewDecoder(r.Body).Decode(targetStruct)
@@ -134,7 +144,7 @@ func StackDriver(ctx context.Context) *errorreporting.Client {
 	// project setup
 	projectID, err := GetEnvVar(GoogleCloudProjectIDEnvVarName)
 	if err != nil {
-		log.WithFields(log.Fields{
+		Logger().WithFields(log.Fields{
 			"environment variable name": GoogleCloudProjectIDEnvVarName,
 			"error":                     err,
 		}).Error("Unable to determine the Google Cloud Project, can't set up StackDriver")
@@ -144,7 +154,7 @@ func StackDriver(ctx context.Context) *errorreporting.Client {
 	// logging
 	loggingClient, err := logging.NewClient(ctx, projectID)
 	if err != nil {
-		log.WithFields(log.Fields{
+		Logger().WithFields(log.Fields{
 			"project ID": projectID,
 			"error":      err,
 		}).Error("Unable to initialize logging client")
@@ -156,7 +166,7 @@ func StackDriver(ctx context.Context) *errorreporting.Client {
 	errorClient, err := errorreporting.NewClient(ctx, projectID, errorreporting.Config{
 		ServiceName: AppName,
 		OnError: func(err error) {
-			log.WithFields(log.Fields{
+			Logger().WithFields(log.Fields{
 				"project ID":   projectID,
 				"service name": AppName,
 				"error":        err,
@@ -164,7 +174,7 @@ func StackDriver(ctx context.Context) *errorreporting.Client {
 		},
 	})
 	if err != nil {
-		log.WithFields(log.Fields{
+		Logger().WithFields(log.Fields{
 			"error": err,
 		}).Error("Unable to initialize error client")
 		return nil
@@ -176,7 +186,7 @@ func StackDriver(ctx context.Context) *errorreporting.Client {
 		ProjectID: projectID,
 	})
 	if err != nil {
-		log.WithFields(log.Fields{
+		Logger().WithFields(log.Fields{
 			"project ID": projectID,
 			"error":      err,
 		}).Info("Unable to initialize tracing")
@@ -191,7 +201,7 @@ func StackDriver(ctx context.Context) *errorreporting.Client {
 		ProjectID:      projectID,
 	})
 	if err != nil {
-		log.WithFields(log.Fields{
+		Logger().WithFields(log.Fields{
 			"project ID":      projectID,
 			"service name":    AppName,
 			"service version": AppVersion,
@@ -208,7 +218,6 @@ func StackDriver(ctx context.Context) *errorreporting.Client {
 // status.
 // TODO: Move to common helpers
 func WriteJSONResponse(w http.ResponseWriter, source interface{}, status int) {
-	w.WriteHeader(status) // must come first...otherwise the first call to Write... sets an implicit 200
 	content, errMap := json.Marshal(source)
 	if errMap != nil {
 		msg := fmt.Sprintf("error when marshalling %#v to JSON bytes: %#v", source, errMap)
@@ -216,7 +225,9 @@ func WriteJSONResponse(w http.ResponseWriter, source interface{}, status int) {
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
+	// headers must be set before the status is written, they are ignored afterwards
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status) // must come before Write...otherwise the first call to Write... sets an implicit 200
 	_, errMap = w.Write(content)
 	if errMap != nil {
 		msg := fmt.Sprintf(
@@ -232,7 +243,7 @@ func WriteJSONResponse(w http.ResponseWriter, source interface{}, status int) {
 func CloseStackDriverLoggingClient(loggingClient *logging.Client) {
 	err := loggingClient.Close()
 	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("Unable to close StackDriver logging client")
+		Logger().WithFields(log.Fields{"error": err}).Error("Unable to close StackDriver logging client")
 	}
 }
 
@@ -242,7 +253,7 @@ func CloseStackDriverLoggingClient(loggingClient *logging.Client) {
 func CloseStackDriverErrorClient(errorClient *errorreporting.Client) {
 	err := errorClient.Close()
 	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("Unable to close StackDriver error client")
+		Logger().WithFields(log.Fields{"error": err}).Error("Unable to close StackDriver error client")
 	}
 }
 
@@ -277,7 +288,7 @@ func StartTestServer(ctx context.Context, prepareServer PrepareServer, allowedOr
 	}
 
 	if IsDebug() {
-		log.Printf("LISTENING on port %d", port)
+		Logger().Printf("LISTENING on port %d", port)
 	}
 
 	// start serving
@@ -285,7 +296,7 @@ func StartTestServer(ctx context.Context, prepareServer PrepareServer, allowedOr
 		err := srv.Serve(l)
 		if err != nil {
 			if IsDebug() {
-				log.Printf("serve error: %s", err)
+				Logger().Printf("serve error: %s", err)
 			}
 		}
 	}()
@@ -295,12 +306,63 @@ func StartTestServer(ctx context.Context, prepareServer PrepareServer, allowedOr
 	return srv, baseURL, nil
 }
 
+// NewServer returns a http server that listens on the supplied port with sensible
+// read, write and idle timeouts
+func NewServer(port int, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           handler,
+		ReadTimeout:       ServerReadTimeout,
+		ReadHeaderTimeout: ServerReadHeaderTimeout,
+		WriteTimeout:      ServerWriteTimeout,
+		IdleTimeout:       ServerIdleTimeout,
+	}
+}
+
+// StartServer starts a http server on the supplied port and blocks until the server fails,
+// the supplied context is cancelled or the process receives SIGINT/SIGTERM.
+//
+// On cancellation or signal, the server stops accepting new connections and in-flight
+// requests are given up to ServerShutdownTimeout to complete.
+func StartServer(ctx context.Context, port int, handler http.Handler) error {
+	srv := NewServer(port, handler)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		Logger().Infof("Server running at port %v", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("server error: %w", err)
+	case <-ctx.Done():
+	}
+
+	Logger().Infof("Shutting down the server at port %v", srv.Addr)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ServerShutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		return fmt.Errorf("unable to gracefully shut down the server: %w", err)
+	}
+	return nil
+}
+
 // HealthStatusCheck endpoint to check if the server is working.
 func HealthStatusCheck(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewEncoder(w).Encode(true)
 	if err != nil {
-		log.Fatal(err)
+		Logger().Fatal(err)
 	}
 
 }
@@ -6,10 +6,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/errorreporting"
 	"cloud.google.com/go/logging"
@@ -72,6 +75,41 @@ func TestRequestDebugMiddleware(t *testing.T) {
 	h.ServeHTTP(rw1, req1)
 }
 
+func TestRequestDebugMiddleware_Redacts(t *testing.T) {
+	initialLogger := serverutils.Logger()
+	defer serverutils.SetLogger(initialLogger)
+	initialDebug := os.Getenv(serverutils.DebugEnvVarName)
+	defer os.Setenv(serverutils.DebugEnvVarName, initialDebug)
+	os.Setenv(serverutils.DebugEnvVarName, "true")
+
+	buf := &bytes.Buffer{}
+	l := log.New()
+	l.SetOutput(buf)
+	serverutils.SetLogger(l)
+
+	var received http.Header
+	var receivedBody []byte
+	h := serverutils.RequestDebugMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		receivedBody = body
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"email": "jane@example.com"}`))
+	request.Header.Set("Cookie", "session=secret-session")
+	request.Header.Set("Authorization", "Bearer secret-token")
+	h.ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.NotContains(t, buf.String(), "secret-session")
+	assert.NotContains(t, buf.String(), "secret-token")
+	assert.NotContains(t, buf.String(), "jane@example.com")
+
+	// the next handler still gets the original request
+	assert.Equal(t, "session=secret-session", received.Get("Cookie"))
+	assert.Equal(t, `{"email": "jane@example.com"}`, string(receivedBody))
+}
+
 func TestLogStartupError(t *testing.T) {
 	type args struct {
 		ctx context.Context
@@ -396,3 +434,67 @@ func healthCheckServer(ctx context.Context, port int, allowedOrigins []string) *
 	return srv
 
 }
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("unable to find a free port: %s", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestNewServer(t *testing.T) {
+	srv := serverutils.NewServer(8080, http.NotFoundHandler())
+	assert.Equal(t, ":8080", srv.Addr)
+	assert.Equal(t, serverutils.ServerReadTimeout, srv.ReadTimeout)
+	assert.Equal(t, serverutils.ServerWriteTimeout, srv.WriteTimeout)
+	assert.Equal(t, serverutils.ServerIdleTimeout, srv.IdleTimeout)
+}
+
+func TestStartServer(t *testing.T) {
+	port := freePort(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/health", serverutils.HealthStatusCheck)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serverutils.StartServer(ctx, port, handler)
+	}()
+
+	addr := net.JoinHostPort("localhost", strconv.Itoa(port))
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = http.Get("http://" + addr + "/health")
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.Nil(t, err)
+	if resp != nil {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Nil(t, resp.Body.Close())
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(serverutils.ServerShutdownTimeout):
+		t.Errorf("StartServer() did not return after the context was cancelled")
+	}
+}
+
+func TestStartServer_PortInUse(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	err = serverutils.StartServer(context.Background(), port, http.NotFoundHandler())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,91 @@
+package serverutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SMSStatus is the delivery status of a SMS
+type SMSStatus string
+
+// SMS delivery statuses
+const (
+	SMSStatusQueued    SMSStatus = "QUEUED"
+	SMSStatusSent      SMSStatus = "SENT"
+	SMSStatusDelivered SMSStatus = "DELIVERED"
+	SMSStatusFailed    SMSStatus = "FAILED"
+)
+
+// SMSResult is the outcome of sending a SMS to one recipient
+type SMSResult struct {
+	MessageID   string
+	PhoneNumber string
+	Status      SMSStatus
+
+	// Reason explains failures
+	Reason string
+}
+
+// SMSDeliveryReport is a delivery status update sent by a SMS provider
+type SMSDeliveryReport struct {
+	MessageID     string
+	PhoneNumber   string
+	Status        SMSStatus
+	FailureReason string
+}
+
+// SMSDeliveryReportFunc records a delivery report e.g updates the status of the stored message
+type SMSDeliveryReportFunc func(ctx context.Context, report SMSDeliveryReport) error
+
+// SMSSender sends SMS.
+//
+// The results hold the provider's message ID of each recipient, which delivery
+// reports refer to.
+type SMSSender interface {
+	SendSMS(ctx context.Context, to []string, message string) ([]SMSResult, error)
+}
+
+// NormalizePhoneNumber returns the E.164 form (e.g +254711223344) of a phone number.
+//
+// Spaces, dashes, dots and brackets are ignored. Numbers in the national format
+// (e.g 0711 223 344) get the supplied default country calling code (e.g 254).
+func NormalizePhoneNumber(phone string, defaultCountryCode string) (string, error) {
+	cleaned := stripPhoneSeparators(strings.TrimSpace(phone))
+
+	var digits string
+	switch {
+	case strings.HasPrefix(cleaned, "+"):
+		digits = cleaned[1:]
+	case strings.HasPrefix(cleaned, "00"):
+		digits = cleaned[2:]
+	case strings.HasPrefix(cleaned, "0"):
+		digits = strings.TrimPrefix(defaultCountryCode, "+") + cleaned[1:]
+	default:
+		digits = cleaned
+	}
+
+	for _, r := range digits {
+		if !unicode.IsDigit(r) || r > unicode.MaxASCII {
+			return "", fmt.Errorf("%q is not a valid phone number", phone)
+		}
+	}
+	// E.164 numbers have at most 15 digits; the shortest numbers in use have 8
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", fmt.Errorf("%q is not a valid phone number", phone)
+	}
+	return "+" + digits, nil
+}
+
+// stripPhoneSeparators removes the spaces, dashes, dots and brackets used to group the digits
+// of a phone number
+func stripPhoneSeparators(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, phone)
+}
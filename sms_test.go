@@ -0,0 +1,38 @@
+package serverutils_test
+
+import (
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		phone   string
+		want    string
+		wantErr bool
+	}{
+		{name: "international format", phone: "+254711223344", want: "+254711223344"},
+		{name: "international format with spaces", phone: " +254 711 223-344 ", want: "+254711223344"},
+		{name: "international dialing prefix", phone: "00254711223344", want: "+254711223344"},
+		{name: "national format", phone: "0711 223 344", want: "+254711223344"},
+		{name: "without plus", phone: "254711223344", want: "+254711223344"},
+		{name: "letters", phone: "+2547112233ab", wantErr: true},
+		{name: "too short", phone: "+2547", wantErr: true},
+		{name: "too long", phone: "+2547112233445566", wantErr: true},
+		{name: "empty", phone: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := serverutils.NormalizePhoneNumber(tt.phone, "254")
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package serverutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers that Cloud Tasks sets on the requests it dispatches
+const (
+	CloudTasksQueueNameHeader      = "X-CloudTasks-QueueName"
+	CloudTasksTaskNameHeader       = "X-CloudTasks-TaskName"
+	CloudTasksRetryCountHeader     = "X-CloudTasks-TaskRetryCount"
+	CloudTasksExecutionCountHeader = "X-CloudTasks-TaskExecutionCount"
+)
+
+// TaskQueue enqueues HTTP tasks that are dispatched to a service at a later time.
+//
+// EnqueueJSONTask schedules a POST of the JSON encoded payload to the supplied URL.
+// A zero schedule time dispatches the task immediately. The name of the created task is
+// returned.
+type TaskQueue interface {
+	EnqueueJSONTask(ctx context.Context, queue string, url string, payload interface{}, schedule time.Time) (string, error)
+}
+
+// CloudTask describes the task a request was dispatched for
+type CloudTask struct {
+	QueueName      string
+	TaskName       string
+	RetryCount     int
+	ExecutionCount int
+}
+
+// VerifyCloudTask protects task handlers so that only authentic tasks are processed.
+//
+// The request must carry a token that the verifier accepts e.g a GoogleIDTokenVerifier
+// for tasks created with an OIDC token. When allowedQueues are supplied, tasks from other
+// queues are rejected with a 401 status. The task is available to the next handler
+// via CloudTaskFromContext.
+func VerifyCloudTask(verifier InterserviceTokenVerifier, allowedQueues ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return VerifyInterserviceJWT(verifier)(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				task, err := cloudTaskFromRequest(r)
+				if err != nil {
+					WriteJSONResponse(w, ErrorMap(err), http.StatusUnauthorized)
+					return
+				}
+				if len(allowedQueues) > 0 && !containsString(allowedQueues, task.QueueName) {
+					WriteJSONResponse(w, ErrorMap(fmt.Errorf("tasks from the %s queue are not allowed", task.QueueName)), http.StatusUnauthorized)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), cloudTaskContextKey, task)
+				next.ServeHTTP(w, r.WithContext(ctx))
+			},
+		))
+	}
+}
+
+// CloudTaskFromContext returns the task verified by VerifyCloudTask
+func CloudTaskFromContext(ctx context.Context) (*CloudTask, bool) {
+	task, ok := ctx.Value(cloudTaskContextKey).(*CloudTask)
+	return task, ok
+}
+
+func cloudTaskFromRequest(r *http.Request) (*CloudTask, error) {
+	task := &CloudTask{
+		QueueName: r.Header.Get(CloudTasksQueueNameHeader),
+		TaskName:  r.Header.Get(CloudTasksTaskNameHeader),
+	}
+	if task.QueueName == "" || task.TaskName == "" {
+		return nil, fmt.Errorf("the request was not dispatched by Cloud Tasks")
+	}
+
+	// the counts are informational, malformed values are treated as zero
+	task.RetryCount, _ = strconv.Atoi(r.Header.Get(CloudTasksRetryCountHeader))
+	task.ExecutionCount, _ = strconv.Atoi(r.Header.Get(CloudTasksExecutionCountHeader))
+	return task, nil
+}
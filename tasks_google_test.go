@@ -0,0 +1,24 @@
+package serverutils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCloudTasksQueue(t *testing.T) {
+	_, err := serverutils.NewCloudTasksQueue(context.Background(), "", "europe-west1", "tasks@example.iam.gserviceaccount.com")
+	assert.NotNil(t, err)
+
+	queue, err := serverutils.NewCloudTasksQueue(context.Background(), "project", "europe-west1", "tasks@example.iam.gserviceaccount.com")
+	if err != nil {
+		// without Google credentials the client can't be initialized
+		t.Skipf("unable to initialize the Cloud Tasks client: %v", err)
+	}
+
+	_, err = queue.EnqueueJSONTask(context.Background(), "queue", "not a url", nil, time.Time{})
+	assert.NotNil(t, err)
+}
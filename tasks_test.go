@@ -0,0 +1,57 @@
+package serverutils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCloudTask(t *testing.T) {
+	const audience = "https://engagement.example.com"
+	secret := []byte("secret")
+	verifier := &serverutils.HMACTokenVerifier{Secret: secret, Audience: audience}
+	source := &serverutils.HMACTokenSource{Issuer: "tasks", Secret: secret}
+	token, err := source.Token(context.Background(), audience)
+	assert.Nil(t, err)
+
+	handler := serverutils.VerifyCloudTask(verifier, "nudges")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		task, ok := serverutils.CloudTaskFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "task-1", task.TaskName)
+		assert.Equal(t, 2, task.RetryCount)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		token      string
+		queue      string
+		wantStatus int
+	}{
+		{name: "authentic task", token: token, queue: "nudges", wantStatus: http.StatusOK},
+		{name: "no token", queue: "nudges", wantStatus: http.StatusUnauthorized},
+		{name: "not dispatched by cloud tasks", token: token, wantStatus: http.StatusUnauthorized},
+		{name: "queue not allowed", token: token, queue: "other", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tasks/expire-nudge", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.queue != "" {
+				req.Header.Set(serverutils.CloudTasksQueueNameHeader, tt.queue)
+				req.Header.Set(serverutils.CloudTasksTaskNameHeader, "task-1")
+				req.Header.Set(serverutils.CloudTasksRetryCountHeader, "2")
+			}
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+			assert.Equal(t, tt.wantStatus, rw.Code)
+		})
+	}
+}
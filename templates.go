@@ -0,0 +1,149 @@
+package serverutils
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TemplateFuncs returns the helpers that are available to templates in a TemplateRegistry:
+//
+//	upper, lower, capitalize, trim: string case and whitespace helpers
+//	default: `{{ default "there" .Name }}` uses "there" when .Name is empty
+//	join: `{{ join ", " .Items }}` joins a list of strings
+//	truncate: `{{ truncate 20 .Message }}` shortens text to at most 20 characters
+//	date: `{{ date "02 Jan 2006" .Time }}` formats a time
+func TemplateFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"capitalize": capitalize,
+		"trim":       strings.TrimSpace,
+		"default":    defaultValue,
+		"join":       join,
+		"truncate":   truncate,
+		"date":       formatDate,
+	}
+}
+
+// TemplateRegistry keeps named templates for user-facing copy e.g notification
+// messages, email bodies and SMS text.
+//
+// Templates are rendered with `missingkey=error` so rendering fails when a variable
+// that the template refers to is not supplied. HTML templates escape their variables.
+type TemplateRegistry struct {
+	mu   sync.RWMutex
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// NewTemplateRegistry returns an initialized, empty template registry
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+}
+
+// RegisterText parses and registers a plain text template e.g for SMS or push notifications
+func (r *TemplateRegistry) RegisterText(name string, source string) error {
+	tmpl, err := texttemplate.New(name).Funcs(TemplateFuncs()).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return fmt.Errorf("unable to parse the %s template: %w", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.text[name] = tmpl
+	return nil
+}
+
+// RegisterHTML parses and registers a HTML template e.g for email bodies
+func (r *TemplateRegistry) RegisterHTML(name string, source string) error {
+	tmpl, err := htmltemplate.New(name).Funcs(TemplateFuncs()).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return fmt.Errorf("unable to parse the %s template: %w", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.html[name] = tmpl
+	return nil
+}
+
+// RenderText renders the named plain text template with the supplied variables
+func (r *TemplateRegistry) RenderText(name string, vars map[string]interface{}) (string, error) {
+	r.mu.RLock()
+	tmpl, ok := r.text[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no text template named %s", name)
+	}
+
+	var out bytes.Buffer
+	err := tmpl.Execute(&out, vars)
+	if err != nil {
+		return "", fmt.Errorf("unable to render the %s template: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// RenderHTML renders the named HTML template with the supplied variables
+func (r *TemplateRegistry) RenderHTML(name string, vars map[string]interface{}) (string, error) {
+	r.mu.RLock()
+	tmpl, ok := r.html[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no HTML template named %s", name)
+	}
+
+	var out bytes.Buffer
+	err := tmpl.Execute(&out, vars)
+	if err != nil {
+		return "", fmt.Errorf("unable to render the %s template: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func defaultValue(fallback interface{}, value interface{}) interface{} {
+	if value == nil {
+		return fallback
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return fallback
+	}
+	return value
+}
+
+func join(sep string, values []string) string {
+	return strings.Join(values, sep)
+}
+
+func truncate(length int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	if length <= 3 {
+		return string(runes[:length])
+	}
+	return string(runes[:length-3]) + "..."
+}
+
+func formatDate(layout string, t time.Time) string {
+	return t.Format(layout)
+}
@@ -0,0 +1,83 @@
+package serverutils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateRegistry_RenderText(t *testing.T) {
+	registry := serverutils.NewTemplateRegistry()
+	assert.Nil(t, registry.RegisterText(
+		"appointment",
+		`{{ capitalize (default "there" .Name) }}, your {{ lower .Kind }} is on {{ date "02 Jan 2006" .Date }}. {{ truncate 10 .Note }}`,
+	))
+	assert.NotNil(t, registry.RegisterText("broken", "{{ .Name "))
+
+	tests := []struct {
+		name    string
+		vars    map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "all variables",
+			vars: map[string]interface{}{
+				"Name": "jane",
+				"Kind": "CHECKUP",
+				"Date": time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+				"Note": "Bring your records",
+			},
+			want: "Jane, your checkup is on 01 Jun 2021. Bring y...",
+		},
+		{
+			name: "default used",
+			vars: map[string]interface{}{
+				"Name": "",
+				"Kind": "visit",
+				"Date": time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+				"Note": "",
+			},
+			want: "There, your visit is on 01 Jun 2021. ",
+		},
+		{
+			name:    "missing variable",
+			vars:    map[string]interface{}{"Name": "jane"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.RenderText("appointment", tt.vars)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := registry.RenderText("unknown", nil)
+	assert.NotNil(t, err)
+}
+
+func TestTemplateRegistry_RenderHTML(t *testing.T) {
+	registry := serverutils.NewTemplateRegistry()
+	assert.Nil(t, registry.RegisterHTML("welcome", `<p>Hello {{ .Name }}, you have {{ join ", " .Items }}</p>`))
+
+	got, err := registry.RenderHTML("welcome", map[string]interface{}{
+		"Name":  "<b>jane</b>",
+		"Items": []string{"a", "b"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "<p>Hello &lt;b&gt;jane&lt;/b&gt;, you have a, b</p>", got)
+
+	_, err = registry.RenderHTML("welcome", map[string]interface{}{"Name": "jane"})
+	assert.NotNil(t, err)
+
+	_, err = registry.RenderText("welcome", nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,183 @@
+package testutils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+)
+
+// FakeOAuthTokenPath is the path of the token endpoint of FakeOAuthServer
+const FakeOAuthTokenPath = "/oauth2/token/"
+
+// FakeOAuthServer is an OAuth2 authorization server for tests. It implements the token
+// endpoint with the password and refresh token grants, so that clients of an auth server can
+// be tested without calling a real one.
+//
+// The scope and token lifetime can be changed with SetScope and SetExpiresIn at any time.
+// The server must be closed when the test is done.
+type FakeOAuthServer struct {
+	*httptest.Server
+
+	clientID     string
+	clientSecret string
+
+	mu            sync.Mutex
+	scope         string
+	expiresIn     time.Duration
+	users         map[string]string
+	accessTokens  map[string]time.Time
+	refreshTokens map[string]string
+	failures      int
+}
+
+// NewFakeOAuthServer starts a fake auth server that accepts the supplied client credentials
+func NewFakeOAuthServer(clientID, clientSecret string) *FakeOAuthServer {
+	s := &FakeOAuthServer{
+		clientID:      clientID,
+		clientSecret:  clientSecret,
+		scope:         "read write",
+		expiresIn:     time.Hour,
+		users:         make(map[string]string),
+		accessTokens:  make(map[string]time.Time),
+		refreshTokens: make(map[string]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(FakeOAuthTokenPath, s.token)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// TokenURL returns the URL of the token endpoint
+func (s *FakeOAuthServer) TokenURL() string {
+	return s.URL + FakeOAuthTokenPath
+}
+
+// AddUser adds a user that can log in with the password grant
+func (s *FakeOAuthServer) AddUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = password
+}
+
+// SetScope changes the scope of the tokens issued by the server
+func (s *FakeOAuthServer) SetScope(scope string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scope = scope
+}
+
+// SetExpiresIn changes the lifetime of the access tokens issued by the server
+func (s *FakeOAuthServer) SetExpiresIn(expiresIn time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expiresIn = expiresIn
+}
+
+// FailNext makes the next n token requests fail with a 503 status e.g to test retries
+func (s *FakeOAuthServer) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// ValidAccessToken reports whether the supplied access token was issued by the server and
+// has not expired
+func (s *FakeOAuthServer) ValidAccessToken(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expires, ok := s.accessTokens[token]
+	return ok && time.Now().Before(expires)
+}
+
+func (s *FakeOAuthServer) token(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		writeOAuthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "the server is unavailable")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", "the token endpoint only accepts POST requests")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "the request body is not a valid form")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.clientID || clientSecret != s.clientSecret {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "the client credentials are invalid")
+		return
+	}
+
+	var username string
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		username = r.PostForm.Get("username")
+		password, ok := s.users[username]
+		if !ok || password != r.PostForm.Get("password") {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "the username or password is invalid")
+			return
+		}
+	case "refresh_token":
+		refreshToken := r.PostForm.Get("refresh_token")
+		username, ok = s.refreshTokens[refreshToken]
+		if !ok {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "the refresh token is invalid")
+			return
+		}
+		// refresh tokens are rotated
+		delete(s.refreshTokens, refreshToken)
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "only the password and refresh_token grants are supported")
+		return
+	}
+
+	accessToken, err := fakeOAuthToken()
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	refreshToken, err := fakeOAuthToken()
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	s.accessTokens[accessToken] = time.Now().Add(s.expiresIn)
+	s.refreshTokens[refreshToken] = username
+
+	w.Header().Set("Cache-Control", "no-store")
+	serverutils.WriteJSONResponse(w, map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(s.expiresIn.Seconds()),
+		"refresh_token": refreshToken,
+		"scope":         s.scope,
+	}, http.StatusOK)
+}
+
+// writeOAuthError writes an error response in the format of RFC 6749 section 5.2
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	serverutils.WriteJSONResponse(w, map[string]string{"error": code, "error_description": description}, status)
+}
+
+func fakeOAuthToken() (string, error) {
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate a token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
@@ -0,0 +1,109 @@
+package testutils_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func requestFakeOAuthToken(t *testing.T, server *testutils.FakeOAuthServer, form url.Values) (int, map[string]interface{}) {
+	resp, err := http.PostForm(server.TokenURL(), form)
+	assert.Nil(t, err)
+
+	body := map[string]interface{}{}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Nil(t, resp.Body.Close())
+	return resp.StatusCode, body
+}
+
+func TestFakeOAuthServer(t *testing.T) {
+	server := testutils.NewFakeOAuthServer("client", "secret")
+	defer server.Close()
+	server.AddUser("jane", "pass")
+
+	login := url.Values{
+		"grant_type":    {"password"},
+		"username":      {"jane"},
+		"password":      {"pass"},
+		"client_id":     {"client"},
+		"client_secret": {"secret"},
+	}
+	status, body := requestFakeOAuthToken(t, server, login)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Equal(t, "read write", body["scope"])
+	assert.True(t, server.ValidAccessToken(body["access_token"].(string)))
+
+	refresh := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {body["refresh_token"].(string)},
+		"client_id":     {"client"},
+		"client_secret": {"secret"},
+	}
+	status, refreshed := requestFakeOAuthToken(t, server, refresh)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEqual(t, body["access_token"], refreshed["access_token"])
+
+	// refresh tokens are single use
+	status, body = requestFakeOAuthToken(t, server, refresh)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	server.FailNext(1)
+	status, body = requestFakeOAuthToken(t, server, login)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "temporarily_unavailable", body["error"])
+	status, _ = requestFakeOAuthToken(t, server, login)
+	assert.Equal(t, http.StatusOK, status)
+
+	server.SetScope("read")
+	server.SetExpiresIn(time.Minute)
+	status, body = requestFakeOAuthToken(t, server, login)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "read", body["scope"])
+	assert.Equal(t, float64(60), body["expires_in"])
+}
+
+func TestFakeOAuthServer_Errors(t *testing.T) {
+	server := testutils.NewFakeOAuthServer("client", "secret")
+	defer server.Close()
+	server.AddUser("jane", "pass")
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "wrong password",
+			form:       url.Values{"grant_type": {"password"}, "username": {"jane"}, "password": {"wrong"}, "client_id": {"client"}, "client_secret": {"secret"}},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid_grant",
+		},
+		{
+			name:       "wrong client",
+			form:       url.Values{"grant_type": {"password"}, "username": {"jane"}, "password": {"pass"}, "client_id": {"client"}, "client_secret": {"wrong"}},
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "invalid_client",
+		},
+		{
+			name:       "unsupported grant",
+			form:       url.Values{"grant_type": {"client_credentials"}, "client_id": {"client"}, "client_secret": {"secret"}},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "unsupported_grant_type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := requestFakeOAuthToken(t, server, tt.form)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantError, body["error"])
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Package testutils contains helpers for testing services built with serverutils.
+//
+// It imports the testing package, so it should only be imported from tests.
+package testutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+)
+
+// ExecuteGraphQL serves the supplied GraphQL request with the handler and decodes the response.
+//
+// The test fails immediately if the response is not a GraphQL JSON response.
+func ExecuteGraphQL(t testing.TB, handler http.Handler, req *http.Request) serverutils.GraphQLResponse {
+	t.Helper()
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+
+	resp := serverutils.GraphQLResponse{}
+	err := json.Unmarshal(rw.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("unable to decode the GraphQL response %q: %v", rw.Body.String(), err)
+	}
+	resp.StatusCode = rw.Code
+	return resp
+}
+
+// AssertGoldenJSON compares the supplied JSON with the contents of a golden file.
+//
+// Both are compared after normalizing their formatting. When UPDATE_GOLDEN_FILES is
+// turned on in the environment, the golden file is (re)written with the supplied JSON instead.
+func AssertGoldenJSON(t testing.TB, goldenPath string, actual []byte) {
+	t.Helper()
+
+	normalized, err := normalizeJSON(actual)
+	if err != nil {
+		t.Fatalf("the actual value is not valid JSON: %v", err)
+	}
+
+	if serverutils.BoolEnv(serverutils.UpdateGoldenFilesEnvVarName) {
+		err := os.MkdirAll(filepath.Dir(goldenPath), 0750)
+		if err != nil {
+			t.Fatalf("unable to create the golden file directory: %v", err)
+		}
+		err = os.WriteFile(goldenPath, normalized, 0600)
+		if err != nil {
+			t.Fatalf("unable to write the golden file %s: %v", goldenPath, err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("unable to read the golden file %s (set %s=true to create it): %v", goldenPath, serverutils.UpdateGoldenFilesEnvVarName, err)
+	}
+	expected, err := normalizeJSON(golden)
+	if err != nil {
+		t.Fatalf("the golden file %s is not valid JSON: %v", goldenPath, err)
+	}
+
+	if !bytes.Equal(expected, normalized) {
+		t.Errorf("the JSON does not match the golden file %s\nexpected:\n%s\nactual:\n%s", goldenPath, expected, normalized)
+	}
+}
+
+// normalizeJSON re-indents JSON with sorted object keys so that formatting differences are ignored
+func normalizeJSON(content []byte) ([]byte, error) {
+	var value interface{}
+	err := json.Unmarshal(content, &value)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(normalized, '\n'), nil
+}
@@ -0,0 +1,101 @@
+package testutils_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/savannahghi/serverutils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+const listPayersQuery = `query ListPayers($first: Int) { listPayers(first: $first) { name } }`
+
+func graphqlHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := serverutils.GraphQLRequest{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		assert.Nil(t, err)
+
+		if r.Header.Get("Authorization") == "" {
+			serverutils.WriteJSONResponse(w, map[string]interface{}{
+				"errors": []map[string]interface{}{{"message": "unauthenticated", "path": []string{"listPayers"}}},
+			}, http.StatusOK)
+			return
+		}
+		serverutils.WriteJSONResponse(w, map[string]interface{}{
+			"data": map[string]interface{}{
+				"listPayers": []map[string]interface{}{{"name": "Jubilee"}},
+				"first":      req.Variables["first"],
+			},
+		}, http.StatusOK)
+	})
+}
+
+func TestExecuteGraphQL(t *testing.T) {
+	req, err := serverutils.BuildGraphQLRequest(listPayersQuery, map[string]interface{}{"first": 10}, "a-token")
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer a-token", req.Header.Get("Authorization"))
+
+	resp := testutils.ExecuteGraphQL(t, graphqlHandler(t), req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Errors)
+
+	data := struct {
+		ListPayers []struct {
+			Name string `json:"name"`
+		} `json:"listPayers"`
+		First int `json:"first"`
+	}{}
+	assert.Nil(t, resp.DecodeData(&data))
+	assert.Equal(t, "Jubilee", data.ListPayers[0].Name)
+	assert.Equal(t, 10, data.First)
+
+	req, err = serverutils.BuildGraphQLRequest(listPayersQuery, nil, "")
+	assert.Nil(t, err)
+	resp = testutils.ExecuteGraphQL(t, graphqlHandler(t), req)
+	assert.Len(t, resp.Errors, 1)
+	assert.Equal(t, "unauthenticated", resp.Errors[0].Message)
+	assert.NotNil(t, resp.DecodeData(&data))
+
+	_, err = serverutils.BuildGraphQLRequest(listPayersQuery, map[string]interface{}{"bad": make(chan int)}, "")
+	assert.NotNil(t, err)
+}
+
+func TestAssertGoldenJSON(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "testdata", "payers.golden.json")
+
+	t.Setenv(serverutils.UpdateGoldenFilesEnvVarName, "true")
+	testutils.AssertGoldenJSON(t, golden, []byte(`{"b":1,"a":[1,2]}`))
+
+	content, err := os.ReadFile(golden)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `"a": [`)
+
+	t.Setenv(serverutils.UpdateGoldenFilesEnvVarName, "")
+	// formatting and key order are ignored
+	testutils.AssertGoldenJSON(t, golden, []byte(`{"a":[1,2],   "b":1}`))
+
+	mismatch := &recordingT{TB: t}
+	testutils.AssertGoldenJSON(mismatch, golden, []byte(`{"a":[1,2],"b":2}`))
+	assert.True(t, mismatch.failed)
+}
+
+// recordingT records failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+}
+
+func (t *recordingT) Fatalf(format string, args ...interface{}) {
+	t.failed = true
+}
@@ -0,0 +1,88 @@
+package serverutils
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer used to instrument the helpers in this package
+const TracerName = "github.com/savannahghi/serverutils"
+
+// TracingMiddleware starts a server span for every incoming HTTP request.
+//
+// Any trace context sent by the caller (e.g the `traceparent` header) is extracted
+// so that the span joins the caller's distributed trace. The span is propagated
+// through the request context so handlers further down the chain can create child spans.
+func TracingMiddleware(serviceName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+				ctx, span := otel.Tracer(TracerName).Start(
+					ctx,
+					fmt.Sprintf("%s %s", r.Method, r.URL.Path),
+					trace.WithSpanKind(trace.SpanKindServer),
+					trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serviceName, r.URL.Path, r)...),
+				)
+				defer span.End()
+
+				newResponseWriter := NewMetricsResponseWriter(w)
+
+				next.ServeHTTP(newResponseWriter, r.WithContext(ctx))
+
+				span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(newResponseWriter.StatusCode)...)
+				span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(newResponseWriter.StatusCode))
+			},
+		)
+	}
+}
+
+// TracingTransport is a http.RoundTripper that records a client span for every
+// outbound request and injects the trace context headers e.g `traceparent`
+// so that the called service can continue the trace
+type TracingTransport struct {
+	base http.RoundTripper
+}
+
+// NewTracingTransport wraps the supplied transport with tracing.
+// When the supplied transport is nil, http.DefaultTransport is used.
+func NewTracingTransport(base http.RoundTripper) *TracingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &TracingTransport{base: base}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *TracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(TracerName).Start(
+		r.Context(),
+		fmt.Sprintf("HTTP %s", r.Method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(r)...),
+	)
+	defer span.End()
+
+	// a RoundTripper should not modify the original request
+	req := r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(resp.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(resp.StatusCode))
+
+	return resp, nil
+}
@@ -0,0 +1,65 @@
+package serverutils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const sampleTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTracingMiddleware(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var got trace.SpanContext
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	})
+	h := serverutils.TracingMiddleware("test-service")(next)
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("traceparent", sampleTraceParent)
+	h.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	assert.True(t, got.IsValid())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got.TraceID().String())
+}
+
+func TestTracingTransport(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceParent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	carrier := propagation.HeaderCarrier(http.Header{})
+	carrier.Set("traceparent", sampleTraceParent)
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	assert.Nil(t, err)
+
+	client := &http.Client{Transport: serverutils.NewTracingTransport(nil)}
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, resp.Body.Close())
+
+	assert.Contains(t, traceParent, "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Empty(t, req.Header.Get("traceparent"))
+
+	_, err = client.Get("http://127.0.0.1:0/unreachable")
+	assert.NotNil(t, err)
+}
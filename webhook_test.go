@@ -0,0 +1,71 @@
+package serverutils_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"event": "payment.completed"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		header  string
+		body    []byte
+		wantErr bool
+	}{
+		{name: "valid", secret: secret, header: serverutils.SignPayload(secret, body, now), body: body},
+		{
+			name:   "rotated secret",
+			secret: secret,
+			header: serverutils.SignPayload([]byte("old"), body, now) + "," +
+				strings.Split(serverutils.SignPayload(secret, body, now), ",")[1],
+			body: body,
+		},
+		{name: "tampered body", secret: secret, header: serverutils.SignPayload(secret, body, now), body: []byte(`{}`), wantErr: true},
+		{name: "wrong secret", secret: []byte("other"), header: serverutils.SignPayload(secret, body, now), body: body, wantErr: true},
+		{name: "replayed", secret: secret, header: serverutils.SignPayload(secret, body, now.Add(-time.Hour)), body: body, wantErr: true},
+		{name: "from the future", secret: secret, header: serverutils.SignPayload(secret, body, now.Add(time.Hour)), body: body, wantErr: true},
+		{name: "malformed", secret: secret, header: "v1=abc", body: body, wantErr: true},
+		{name: "no secret", header: serverutils.SignPayload(secret, body, now), body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := serverutils.VerifyWebhookSignature(tt.secret, tt.header, tt.body, serverutils.DefaultWebhookTolerance)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestVerifySignedWebhook(t *testing.T) {
+	secret := []byte("secret")
+	body := `{"event": "payment.completed"}`
+
+	handler := serverutils.VerifySignedWebhook(secret, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, body, string(received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/payments", strings.NewReader(body))
+	serverutils.SignWebhookRequest(req, secret, []byte(body))
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/payments", strings.NewReader(body))
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+}
@@ -0,0 +1,319 @@
+package serverutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WhatsAppGraphAPIURL is the base URL of the WhatsApp Business Cloud API
+const WhatsAppGraphAPIURL = "https://graph.facebook.com/v17.0"
+
+// WhatsApp media message types
+const (
+	WhatsAppMediaImage    = "image"
+	WhatsAppMediaVideo    = "video"
+	WhatsAppMediaAudio    = "audio"
+	WhatsAppMediaDocument = "document"
+)
+
+// WhatsAppSender sends messages through the WhatsApp Business Cloud API.
+//
+// Outside the 24 hour window that follows a user's last message, only approved
+// template messages can be sent.
+type WhatsAppSender struct {
+	accessToken   string
+	phoneNumberID string
+	baseURL       string
+	httpClient    *http.Client
+}
+
+// NewWhatsAppSender returns a WhatsApp sender for the supplied business phone number ID
+func NewWhatsAppSender(accessToken, phoneNumberID string) *WhatsAppSender {
+	return &WhatsAppSender{
+		accessToken:   accessToken,
+		phoneNumberID: phoneNumberID,
+		baseURL:       WhatsAppGraphAPIURL,
+		httpClient: &http.Client{
+			Timeout:   InterserviceRequestTimeout,
+			Transport: NewTracingTransport(NewMetricsTransport(nil)),
+		},
+	}
+}
+
+// NewWhatsAppSenderFromEnv returns a WhatsApp sender configured from the WHATSAPP_*
+// environment variables
+func NewWhatsAppSenderFromEnv() (*WhatsAppSender, error) {
+	accessToken, err := GetEnvVar(WhatsAppAccessTokenEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	phoneNumberID, err := GetEnvVar(WhatsAppPhoneNumberIDEnvVarName)
+	if err != nil {
+		return nil, err
+	}
+	return NewWhatsAppSender(accessToken, phoneNumberID), nil
+}
+
+// WithBaseURL points the sender to another API e.g a test server
+func (s *WhatsAppSender) WithBaseURL(url string) *WhatsAppSender {
+	s.baseURL = strings.TrimRight(url, "/")
+	return s
+}
+
+// SendTemplateMessage sends an approved template message whose body placeholders
+// ({{1}}, {{2}}...) are filled with the supplied parameters, in order.
+// It returns the ID of the sent message.
+func (s *WhatsAppSender) SendTemplateMessage(ctx context.Context, to, template, languageCode string, parameters ...string) (string, error) {
+	components := []map[string]interface{}{}
+	if len(parameters) > 0 {
+		values := []map[string]string{}
+		for _, parameter := range parameters {
+			values = append(values, map[string]string{"type": "text", "text": parameter})
+		}
+		components = append(components, map[string]interface{}{"type": "body", "parameters": values})
+	}
+
+	return s.send(ctx, to, "template", map[string]interface{}{
+		"name":       template,
+		"language":   map[string]string{"code": languageCode},
+		"components": components,
+	})
+}
+
+// SendMediaMessage sends the media file at the supplied link, which must be publicly reachable.
+// The caption is not supported for audio and the filename is only used for documents.
+// It returns the ID of the sent message.
+func (s *WhatsAppSender) SendMediaMessage(ctx context.Context, to, mediaType, link, caption, filename string) (string, error) {
+	media := map[string]string{"link": link}
+	switch mediaType {
+	case WhatsAppMediaImage, WhatsAppMediaVideo:
+	case WhatsAppMediaDocument:
+		if filename != "" {
+			media["filename"] = filename
+		}
+	case WhatsAppMediaAudio:
+		caption = ""
+	default:
+		return "", fmt.Errorf("unsupported WhatsApp media type %s", mediaType)
+	}
+	if caption != "" {
+		media["caption"] = caption
+	}
+	return s.send(ctx, to, mediaType, media)
+}
+
+func (s *WhatsAppSender) send(ctx context.Context, to, messageType string, message interface{}) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                strings.TrimPrefix(to, "+"),
+		"type":              messageType,
+		messageType:         message,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal the WhatsApp message: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/messages", s.baseURL, s.phoneNumberID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("unable to compose the WhatsApp request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to send the WhatsApp message: %w", err)
+	}
+	defer closeBody(resp)
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the WhatsApp response: %w", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return "", fmt.Errorf("the WhatsApp API responded with status %d: %s", resp.StatusCode, string(content))
+	}
+
+	sent := struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}{}
+	err = json.Unmarshal(content, &sent)
+	if err != nil || len(sent.Messages) == 0 {
+		return "", fmt.Errorf("unexpected WhatsApp response: %s", string(content))
+	}
+	return sent.Messages[0].ID, nil
+}
+
+// WhatsAppInboundMessage is a message that a user sent to the business phone number
+type WhatsAppInboundMessage struct {
+	ID        string
+	From      string
+	Name      string
+	Timestamp time.Time
+	Type      string
+
+	// Text is the text of text messages and the title of button and list replies
+	Text string
+
+	// ReplyTo is the ID of the message that this message replies to, if any
+	ReplyTo string
+}
+
+// WhatsAppMessageFunc processes an inbound WhatsApp message e.g stores it in a conversation.
+//
+// WhatsApp delivers messages at least once, so it should ignore messages whose ID it has
+// already processed.
+type WhatsAppMessageFunc func(ctx context.Context, message WhatsAppInboundMessage) error
+
+type whatsAppWebhookPayload struct {
+	Entry []struct {
+		Changes []struct {
+			Field string `json:"field"`
+			Value struct {
+				Contacts []struct {
+					WaID    string `json:"wa_id"`
+					Profile struct {
+						Name string `json:"name"`
+					} `json:"profile"`
+				} `json:"contacts"`
+				Messages []struct {
+					ID        string `json:"id"`
+					From      string `json:"from"`
+					Timestamp string `json:"timestamp"`
+					Type      string `json:"type"`
+					Text      struct {
+						Body string `json:"body"`
+					} `json:"text"`
+					Button struct {
+						Text string `json:"text"`
+					} `json:"button"`
+					Interactive struct {
+						ButtonReply struct {
+							Title string `json:"title"`
+						} `json:"button_reply"`
+						ListReply struct {
+							Title string `json:"title"`
+						} `json:"list_reply"`
+					} `json:"interactive"`
+					Context struct {
+						ID string `json:"id"`
+					} `json:"context"`
+				} `json:"messages"`
+			} `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+// WhatsAppWebhookHandler handles the WhatsApp Business webhook.
+//
+// GET requests answer the subscription verification challenge using verifyToken.
+// POST requests must be signed with the app secret (the X-Hub-Signature-256 header); every
+// inbound message they carry is passed to receive. Status updates are ignored.
+//
+// When receive fails, the handler responds with a 500 status and WhatsApp redelivers the
+// whole batch, including the messages that were received before the failure, so receive must
+// deduplicate messages by ID. It panics if verifyToken or appSecret is empty, since an empty
+// secret would let anyone forge webhooks.
+func WhatsAppWebhookHandler(verifyToken, appSecret string, receive WhatsAppMessageFunc) http.HandlerFunc {
+	if verifyToken == "" || appSecret == "" {
+		panic("the WhatsApp webhook verify token and app secret are required")
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			query := r.URL.Query()
+			if query.Get("hub.mode") != "subscribe" || !hmac.Equal([]byte(query.Get("hub.verify_token")), []byte(verifyToken)) {
+				WriteJSONResponse(w, ErrorMap(fmt.Errorf("invalid verification request")), http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, err := w.Write([]byte(query.Get("hub.challenge")))
+			if err != nil {
+				Logger().Println(err)
+			}
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, DefaultMaxBodyBytes))
+		if err != nil {
+			WriteJSONResponse(w, ErrorMap(fmt.Errorf("unable to read the webhook payload: %w", err)), http.StatusBadRequest)
+			return
+		}
+		if !validWhatsAppSignature(r.Header.Get("X-Hub-Signature-256"), body, appSecret) {
+			WriteJSONResponse(w, ErrorMap(fmt.Errorf("invalid webhook signature")), http.StatusUnauthorized)
+			return
+		}
+
+		payload := whatsAppWebhookPayload{}
+		err = json.Unmarshal(body, &payload)
+		if err != nil {
+			WriteJSONResponse(w, ErrorMap(fmt.Errorf("unable to decode the webhook payload: %w", err)), http.StatusBadRequest)
+			return
+		}
+
+		for _, entry := range payload.Entry {
+			for _, change := range entry.Changes {
+				if change.Field != "messages" {
+					continue
+				}
+				names := map[string]string{}
+				for _, contact := range change.Value.Contacts {
+					names[contact.WaID] = contact.Profile.Name
+				}
+				for _, m := range change.Value.Messages {
+					message := WhatsAppInboundMessage{
+						ID:      m.ID,
+						From:    m.From,
+						Name:    names[m.From],
+						Type:    m.Type,
+						ReplyTo: m.Context.ID,
+					}
+					if seconds, err := strconv.ParseInt(m.Timestamp, 10, 64); err == nil {
+						message.Timestamp = time.Unix(seconds, 0).UTC()
+					}
+					switch {
+					case m.Text.Body != "":
+						message.Text = m.Text.Body
+					case m.Button.Text != "":
+						message.Text = m.Button.Text
+					case m.Interactive.ButtonReply.Title != "":
+						message.Text = m.Interactive.ButtonReply.Title
+					default:
+						message.Text = m.Interactive.ListReply.Title
+					}
+
+					err = receive(r.Context(), message)
+					if err != nil {
+						// a failure status makes WhatsApp retry the delivery
+						WriteJSONResponse(w, ErrorMap(err), http.StatusInternalServerError)
+						return
+					}
+				}
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func validWhatsAppSignature(header string, body []byte, appSecret string) bool {
+	signature, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
@@ -0,0 +1,166 @@
+package serverutils_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/savannahghi/serverutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhatsAppSender(t *testing.T) {
+	var received map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/phone-id/messages", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&received))
+		serverutils.WriteJSONResponse(w, map[string]interface{}{
+			"messages": []map[string]string{{"id": "wamid.1"}},
+		}, http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sender := serverutils.NewWhatsAppSender("token", "phone-id").WithBaseURL(srv.URL)
+
+	id, err := sender.SendTemplateMessage(context.Background(), "+254711223344", "appointment_reminder", "en", "Jane", "Monday")
+	assert.Nil(t, err)
+	assert.Equal(t, "wamid.1", id)
+	assert.Equal(t, "254711223344", received["to"])
+	assert.Equal(t, "template", received["type"])
+	template := received["template"].(map[string]interface{})
+	assert.Equal(t, "appointment_reminder", template["name"])
+	assert.Len(t, template["components"], 1)
+
+	id, err = sender.SendMediaMessage(context.Background(), "+254711223344", serverutils.WhatsAppMediaDocument, "https://example.com/report.pdf", "Your report", "report.pdf")
+	assert.Nil(t, err)
+	assert.Equal(t, "wamid.1", id)
+	document := received["document"].(map[string]interface{})
+	assert.Equal(t, "https://example.com/report.pdf", document["link"])
+	assert.Equal(t, "report.pdf", document["filename"])
+
+	_, err = sender.SendMediaMessage(context.Background(), "+254711223344", "sticker", "https://example.com/a.webp", "", "")
+	assert.NotNil(t, err)
+}
+
+func TestWhatsAppWebhookHandler(t *testing.T) {
+	var messages []serverutils.WhatsAppInboundMessage
+	handler := serverutils.WhatsAppWebhookHandler("verify-token", "app-secret", func(ctx context.Context, message serverutils.WhatsAppInboundMessage) error {
+		if message.Text == "fail" {
+			return fmt.Errorf("unable to store the message")
+		}
+		messages = append(messages, message)
+		return nil
+	})
+
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("app-secret"))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	payload := func(text string) string {
+		return fmt.Sprintf(`{"object": "whatsapp_business_account", "entry": [{"changes": [{"field": "messages", "value": {
+			"contacts": [{"wa_id": "254711223344", "profile": {"name": "Jane"}}],
+			"messages": [{"id": "wamid.2", "from": "254711223344", "timestamp": "1690000000", "type": "text",
+				"text": {"body": %q}, "context": {"id": "wamid.1"}}]
+		}}]}]}`, text)
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		signature    string
+		wantStatus   int
+		wantMessages int
+	}{
+		{
+			name:       "verification",
+			method:     http.MethodGet,
+			target:     "/whatsapp?hub.mode=subscribe&hub.verify_token=verify-token&hub.challenge=1158201444",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "verification with the wrong token",
+			method:     http.MethodGet,
+			target:     "/whatsapp?hub.mode=subscribe&hub.verify_token=wrong&hub.challenge=1158201444",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:         "inbound message",
+			method:       http.MethodPost,
+			target:       "/whatsapp",
+			body:         payload("Yes"),
+			signature:    sign(payload("Yes")),
+			wantStatus:   http.StatusOK,
+			wantMessages: 1,
+		},
+		{
+			name:       "invalid signature",
+			method:     http.MethodPost,
+			target:     "/whatsapp",
+			body:       payload("Yes"),
+			signature:  sign("something else"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "processing fails",
+			method:     http.MethodPost,
+			target:     "/whatsapp",
+			body:       payload("fail"),
+			signature:  sign(payload("fail")),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages = nil
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set("X-Hub-Signature-256", tt.signature)
+			}
+			rw := httptest.NewRecorder()
+
+			handler.ServeHTTP(rw, req)
+			assert.Equal(t, tt.wantStatus, rw.Code)
+			assert.Len(t, messages, tt.wantMessages)
+			if tt.wantMessages > 0 {
+				assert.Equal(t, "Jane", messages[0].Name)
+				assert.Equal(t, "Yes", messages[0].Text)
+				assert.Equal(t, "wamid.1", messages[0].ReplyTo)
+				assert.Equal(t, int64(1690000000), messages[0].Timestamp.Unix())
+			}
+			if tt.method == http.MethodGet && tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "1158201444", rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestWhatsAppWebhookHandler_MissingSecrets(t *testing.T) {
+	tests := []struct {
+		name        string
+		verifyToken string
+		appSecret   string
+	}{
+		{name: "no verify token", appSecret: "app-secret"},
+		{name: "no app secret", verifyToken: "verify-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				assert.NotNil(t, recover())
+			}()
+			serverutils.WhatsAppWebhookHandler(tt.verifyToken, tt.appSecret, nil)
+			t.Error("missing secrets should panic")
+		})
+	}
+}